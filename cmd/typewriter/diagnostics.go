@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
+	"github.com/natdm/typewriter/diag"
+	log "github.com/sirupsen/logrus"
+)
+
+// diagnostics prints what parsing skipped, and how.
+type diagnostics struct {
+	list diag.List
+
+	// json prints every diagnostic as a JSON array instead of one per line.
+	json bool
+
+	// out is the file to write the diagnostics to, instead of standard error.
+	out string
+
+	// verbose prints info diagnostics as text too.
+	verbose bool
+
+	// werror fails on warnings too.
+	werror bool
+}
+
+// newDiagnostics returns diagnostics printed in format, "text" or "json", to
+// the file out, or standard error when empty.
+func newDiagnostics(format, out string, verbose, werror bool) *diagnostics {
+	switch format {
+	case "", "text":
+		return &diagnostics{out: out, verbose: verbose, werror: werror}
+	case "json":
+		return &diagnostics{json: true, out: out, verbose: verbose, werror: werror}
+	}
+	log.Fatalf("Please pick a -diagnostics format of 'text' or 'json', not %q", format)
+	return nil
+}
+
+// print writes the diagnostics to standard error or their file, compiler style
+// or as JSON, and returns how many fail the run: errors, and warnings with -Werror.
+func (d *diagnostics) print() int {
+	min := diag.Warning
+	if d.json || d.verbose {
+		min = diag.Info
+	}
+	diags := d.list.Diagnostics(min)
+
+	if err := d.write(diags); err != nil {
+		log.WithError(err).Error("error printing diagnostics")
+	}
+
+	failed := 0
+	for _, v := range diags {
+		if v.Severity == diag.Error || (d.werror && v.Severity == diag.Warning) {
+			failed++
+		}
+	}
+	return failed
+}
+
+// write writes diags to standard error, or replaces their file.
+func (d *diagnostics) write(diags []diag.Diagnostic) error {
+	var buf bytes.Buffer
+	var err error
+	if d.json {
+		err = diag.WriteJSON(&buf, diags)
+	} else {
+		err = diag.Print(&buf, diags)
+	}
+	if err != nil {
+		return err
+	}
+	if d.out != "" {
+		return ioutil.WriteFile(d.out, buf.Bytes(), 0644)
+	}
+	_, err = buf.WriteTo(os.Stderr)
+	return err
+}
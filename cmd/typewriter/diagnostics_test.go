@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/natdm/typewriter/diag"
+	"github.com/natdm/typewriter/ir"
+	"github.com/stretchr/testify/suite"
+)
+
+type DiagnosticsTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestDiagnosticsTestSuite(t *testing.T) {
+	suite.Run(t, new(DiagnosticsTestSuite))
+}
+
+func (s *DiagnosticsTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "typewriter")
+	s.Require().NoError(err)
+	s.dir = dir
+}
+
+func (s *DiagnosticsTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *DiagnosticsTestSuite) TestOut() {
+	out := filepath.Join(s.dir, "diagnostics.json")
+	d := newDiagnostics("json", out, false, false)
+	d.list.Report(diag.Diagnostic{Pos: ir.Position{File: "user.go", Line: 7, Column: 6}, Severity: diag.Warning, Type: "Hub", Reason: "not a supported type"})
+	s.Equal(0, d.print())
+
+	bs, err := ioutil.ReadFile(out)
+	s.Require().NoError(err)
+	var diags []map[string]interface{}
+	s.Require().NoError(json.Unmarshal(bs, &diags), "the file is only the JSON array")
+	s.Require().Len(diags, 1)
+	s.Equal("Hub", diags[0]["type"])
+
+	d = newDiagnostics("json", out, false, false)
+	d.print()
+	bs, err = ioutil.ReadFile(out)
+	s.Require().NoError(err)
+	s.JSONEq("[]", string(bs), "the file is replaced")
+}
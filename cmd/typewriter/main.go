@@ -0,0 +1,635 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/natdm/typewriter"
+	"github.com/natdm/typewriter/ir"
+	"github.com/natdm/typewriter/parse"
+	"github.com/natdm/typewriter/template"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	inFlag := flag.String("dir", "./", "dir is to specify what folder to parse types from")
+	fileFlag := flag.String("file", "", "file is to parse a single file. Will override a directory")
+	var langFlags, outFlags stringsFlag
+	flag.Var(&langFlags, "lang", "determine the language. One of 'flow', 'ts', 'elm', 'jsdoc', or a registered language. Repeat with -out for more outputs")
+	templatesFlag := flag.String("templates", "", "directory of template fragments to use instead of -lang")
+	flag.Var(&outFlags, "out", "file and path to save output to, one per -lang")
+	vFlag := flag.Bool("v", false, "verbose logging")
+	recursiveFlag := flag.Bool("r", true, "to recursively ascend all folders in dir")
+	expandEmbeddedFlag := flag.Bool("e", false, "expand embedded structs inline")
+	tsExportFlag := flag.Bool("ts-export", false, "export every Typescript declaration")
+	tsInterfaceFlag := flag.Bool("ts-interface", false, "declare Typescript structs as interfaces")
+	tsExtendsFlag := flag.Bool("ts-extends", false, "declare Typescript structs with embedded types as interfaces extending them")
+	tsReadonlyFlag := flag.Bool("ts-readonly", false, "mark Typescript properties as readonly")
+	flowExactFlag := flag.Bool("flow-exact", false, "make every Flow object exact")
+	flowReadOnlyFlag := flag.Bool("flow-readonly", false, "wrap Flow objects in $ReadOnly<>")
+	flowCovariantFlag := flag.Bool("flow-covariant", false, "mark Flow properties as covariant (+)")
+	enumUnionsFlag := flag.Bool("enum-unions", false, "draw types with declared constants as a union of their values")
+	tsNamespaceFlag := flag.String("ts-namespace", "", "wrap Typescript declarations in a 'declare namespace' block")
+	tsModuleFlag := flag.String("ts-module", "", "wrap Typescript declarations in a 'declare module' block")
+	emitIRFlag := flag.String("emit-ir", "", "file to save the parsed types to, as JSON")
+	fromIRFlag := flag.String("from-ir", "", "JSON file of types to draw instead of parsing Go")
+	checkFlag := flag.Bool("check", false, "compare every -out file with the drawn types, printing a diff and failing when they differ, without writing anything")
+	watchFlag := flag.Bool("watch", false, "keep running, and draw the types again every time a parsed file changes")
+	splitFlag := flag.String("split", "", "draw a file per Go 'package' or per 'type' into the -out directory")
+	indexFlag := flag.Bool("index", false, "with -split, draw an index file re-exporting every file")
+	var includeFlags, excludeFlags stringsFlag
+	flag.Var(&includeFlags, "include", "glob of the Go files to parse, such as 'api/**'. Can be repeated")
+	flag.Var(&excludeFlags, "exclude", "glob of the Go files or directories not to parse, such as 'vendor' or '*_gen.go'. Can be repeated")
+	includeTypesFlag := flag.String("include-types", "", "regular expression of the type names to parse")
+	excludeTypesFlag := flag.String("exclude-types", "", "regular expression of the type names to skip")
+	exportedOnlyFlag := flag.Bool("exported-only", false, "skip unexported types")
+	tagsFlag := flag.String("tags", "", "comma separated build tags to select files with, like go build")
+	goosFlag := flag.String("goos", "", "GOOS to select files with by build constraints and name suffixes. Defaults to the host's")
+	goarchFlag := flag.String("goarch", "", "GOARCH to select files with by build constraints and name suffixes. Defaults to the host's")
+	var rootFlags stringsFlag
+	flag.Var(&rootFlags, "root", "type to draw, with every type it refers to, such as 'Response' or 'api.Response'. Can be repeated")
+	unexportedFieldsFlag := flag.Bool("unexported-fields", false, "draw unexported struct fields, which encoding/json skips")
+	markedOnlyFlag := flag.Bool("marked-only", false, "draw only the types marked with @typewriter or //tw:generate, and the types they refer to")
+	collisionsFlag := flag.String("collisions", "", "how types of the same name in different packages are handled: 'error' or 'prefix'")
+	werrorFlag := flag.Bool("Werror", false, "fail on warnings, such as skipped unsupported types, without writing any output")
+	diagnosticsFlag := flag.String("diagnostics", "text", "how skipped types and fields are printed to standard error: 'text', compiler style, or 'json'")
+	diagnosticsOutFlag := flag.String("diagnostics-out", "", "file to write the diagnostics to, instead of standard error")
+	configFlag := flag.String("config", "", "project file to read, instead of typewriter.yaml or .typewriter.json in the working directory")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *vFlag {
+		log.SetLevel(log.DebugLevel)
+	}
+
+	diags := newDiagnostics(*diagnosticsFlag, *diagnosticsOutFlag, *vFlag, *werrorFlag)
+	if diags.json && diags.out == "" {
+		// Only errors are logged, so standard error is the JSON array unless the run fails.
+		log.SetLevel(log.ErrorLevel)
+	}
+	p := loadProject(*configFlag)
+
+	// Flags set on the command line override the project file.
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "dir":
+			p.Dir, p.Files = *inFlag, nil
+		case "file":
+			p.Files = []string{*fileFlag}
+		case "r":
+			p.Recursive = *recursiveFlag
+		case "e":
+			p.ExpandEmbedded = *expandEmbeddedFlag
+		case "include":
+			p.Include = includeFlags
+		case "exclude":
+			p.Exclude = excludeFlags
+		case "include-types":
+			p.IncludeTypes = *includeTypesFlag
+		case "exclude-types":
+			p.ExcludeTypes = *excludeTypesFlag
+		case "exported-only":
+			p.ExportedOnly = *exportedOnlyFlag
+		case "unexported-fields":
+			p.UnexportedFields = *unexportedFieldsFlag
+		case "marked-only":
+			p.MarkedOnly = *markedOnlyFlag
+		case "root":
+			p.Roots = rootFlags
+		case "tags":
+			p.Tags = nil
+			for _, tag := range strings.Split(*tagsFlag, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					p.Tags = append(p.Tags, tag)
+				}
+			}
+		case "goos":
+			p.GOOS = *goosFlag
+		case "goarch":
+			p.GOARCH = *goarchFlag
+		case "collisions":
+			p.Collisions = *collisionsFlag
+		case "templates":
+			p.Templates = []string{*templatesFlag}
+		case "ts-export":
+			p.TS.Export = *tsExportFlag
+		case "ts-interface":
+			p.TS.Interface = *tsInterfaceFlag
+		case "ts-extends":
+			p.TS.Extends = *tsExtendsFlag
+		case "ts-readonly":
+			p.TS.Readonly = *tsReadonlyFlag
+		case "ts-namespace":
+			p.TS.Namespace = *tsNamespaceFlag
+		case "ts-module":
+			p.TS.Module = *tsModuleFlag
+		case "flow-exact":
+			p.Flow.Exact = *flowExactFlag
+		case "flow-readonly":
+			p.Flow.ReadOnly = *flowReadOnlyFlag
+		case "flow-covariant":
+			p.Flow.Covariant = *flowCovariantFlag
+		case "enum-unions":
+			p.EnumUnions = *enumUnionsFlag
+		}
+	})
+
+	packs, err := p.LoadTemplates()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if *templatesFlag != "" {
+		// The pack of -templates is drawn alone, or as one of the -lang.
+		pack := packs[0].String()
+		if len(langFlags) == 0 {
+			langFlags = append(langFlags, pack)
+		} else if !contains(langFlags, pack) {
+			log.Fatalf("The template pack is not drawn, add -lang %s or drop -templates", pack)
+		}
+	}
+
+	if len(langFlags) > 1 && len(outFlags) != len(langFlags) {
+		log.Fatalln("Please pair every -lang with an -out")
+	}
+	if len(outFlags) > len(langFlags) {
+		log.Fatalln("Please pick a -lang for every -out")
+	}
+	if len(langFlags) > 0 {
+		p.Targets = nil
+		for i, name := range langFlags {
+			t := typewriter.ProjectTarget{Lang: name}
+			if i < len(outFlags) {
+				t.Out = outFlags[i]
+			}
+			p.Targets = append(p.Targets, t)
+		}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		for i := range p.Targets {
+			switch f.Name {
+			case "split":
+				p.Targets[i].Split = *splitFlag
+			case "index":
+				p.Targets[i].Index = *indexFlag
+			}
+		}
+	})
+
+	// Without a language, the parsed types can still be saved with -emit-ir.
+	emitOnly := len(p.Targets) == 0 && *emitIRFlag != ""
+
+	if !emitOnly && len(p.Targets) == 0 {
+		log.Fatalf("Please pick a proper language ['%s']", strings.Join(template.Languages(), "', '"))
+	}
+	if p.TS.Namespace != "" && p.TS.Module != "" {
+		log.Fatalln("Please pick one of -ts-namespace and -ts-module")
+	}
+	if *watchFlag && (*checkFlag || *fromIRFlag != "") {
+		log.Fatalln("Please pick -watch without -check or -from-ir")
+	}
+
+	var targets []typewriter.Target
+	var sinks []sink
+	for _, t := range p.Targets {
+		lang, ok := template.LookupLanguage(t.Lang)
+		if !ok {
+			log.Fatalf("Please pick a proper language ['%s']", strings.Join(template.Languages(), "', '"))
+		}
+		if lang.Spec().ExpandEmbedded && !p.ExpandEmbedded && *fromIRFlag == "" {
+			log.Fatalf("You have to use -e flag with %s, which does not support intersection types", lang)
+		}
+		if (*checkFlag || *watchFlag) && t.Out == "" {
+			log.Fatalf("Please pick an -out for %s to check or watch", lang)
+		}
+
+		split, err := template.ParseSplit(t.Split)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		target := typewriter.Target{Language: lang, Options: p.Options(t), Split: split, Index: t.Index}
+		if split == template.SplitNone {
+			out := &output{path: t.Out}
+			sinks = append(sinks, out)
+			target.Out = &out.buf
+		} else {
+			if t.Out == "" {
+				log.Fatalf("Please pick an -out directory to split %s into", lang)
+			}
+			dir, err := newOutputDir(t.Out, lang, target.Options)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			sinks = append(sinks, dir)
+			target.OutFile = dir.file
+		}
+		targets = append(targets, target)
+	}
+
+	collisions, err := parse.ParseCollisions(p.Collisions)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	c := typewriter.Config{
+		Dir:              p.Dir,
+		Files:            p.Files,
+		Recursive:        p.Recursive,
+		Include:          p.Include,
+		Exclude:          p.Exclude,
+		IncludeTypes:     compile("-include-types", p.IncludeTypes),
+		ExcludeTypes:     compile("-exclude-types", p.ExcludeTypes),
+		ExportedOnly:     p.ExportedOnly,
+		UnexportedFields: p.UnexportedFields,
+		MarkedOnly:       p.MarkedOnly,
+		Roots:            p.Roots,
+		Unions:           p.Unions,
+		Build:            p.Build(),
+		Targets:          targets,
+		ExpandEmbedded:   p.ExpandEmbedded,
+		Collisions:       collisions,
+		Logger:           log.StandardLogger(),
+		Diagnostics:      &diags.list,
+	}
+	if *fromIRFlag != "" {
+		c.Types = readIR(*fromIRFlag)
+	}
+
+	if *watchFlag {
+		watch(c, sinks, diags, *emitIRFlag)
+		return
+	}
+
+	var res typewriter.Result
+	if emitOnly {
+		types, err := typewriter.Parse(context.Background(), c)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		res.Types = types
+	} else {
+		r, err := typewriter.Generate(context.Background(), c)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		res = r
+	}
+	if failed := diags.print(); failed > 0 {
+		log.Fatalf("%d diagnostics fail the run, nothing was written", failed)
+	}
+
+	if *checkFlag {
+		check(sinks)
+		return
+	}
+	outputs, err := drawnFiles(sinks)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, out := range outputs {
+		if _, err := out.write(); err != nil {
+			log.Fatalln(err)
+		}
+		if out.stale {
+			log.WithField("file", out.path).Info("removed stale output")
+		}
+	}
+	if *emitIRFlag != "" {
+		writeIR(*emitIRFlag, res.Types)
+	}
+	log.WithField("output_type_ct", res.Count).Info("Done")
+}
+
+// check prints a diff of every output that differs from its file, and exits
+// non-zero if any does.
+func check(sinks []sink) {
+	outputs, err := drawnFiles(sinks)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	stale := 0
+	for _, out := range outputs {
+		diff, err := out.diff()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if diff != "" {
+			fmt.Print(diff)
+			stale++
+		}
+	}
+	if stale > 0 {
+		log.Fatalf("%d of %d outputs are out of date", stale, len(outputs))
+	}
+	log.WithField("outputs", len(outputs)).Info("Up to date")
+}
+
+// compile compiles a regular expression, or returns nil for an empty one.
+func compile(name, expr string) *regexp.Regexp {
+	if expr == "" {
+		return nil
+	}
+	r, err := regexp.Compile(expr)
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+	return r
+}
+
+// contains reports whether s is one of names.
+func contains(names []string, s string) bool {
+	for _, v := range names {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// drawnFiles returns every file drawn into the sinks, and the stale files
+// to remove from their directories. Files drawn by any sink are not stale.
+func drawnFiles(sinks []sink) ([]*output, error) {
+	var all []*output
+	drawn := make(map[string]bool)
+	for _, s := range sinks {
+		fs, err := s.files()
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fs {
+			if !f.stale {
+				drawn[f.path] = true
+			}
+		}
+		all = append(all, fs...)
+	}
+
+	var files []*output
+	stale := make(map[string]bool)
+	for _, f := range all {
+		if f.stale {
+			if drawn[f.path] || stale[f.path] {
+				continue
+			}
+			stale[f.path] = true
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// loadProject reads the project file at path, or the one in the working
+// directory when path is empty. Without either, it returns the flag defaults.
+func loadProject(path string) *typewriter.Project {
+	if path == "" {
+		found, err := typewriter.FindProject(".")
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if found == "" {
+			return &typewriter.Project{Dir: "./", Recursive: true}
+		}
+		path = found
+	}
+	p, err := typewriter.LoadProject(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	log.WithField("file", path).Debug("read project file")
+	return p
+}
+
+// stringsFlag is a flag that can be repeated, keeping every value in order.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string { return strings.Join(*s, ",") }
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// readIR reads types saved with -emit-ir.
+func readIR(path string) map[string]*ir.Decl {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer f.Close()
+	types, err := ir.Decode(f)
+	if err != nil {
+		log.Fatalf("%s: %v", path, err)
+	}
+	return types
+}
+
+// writeIR saves types as JSON.
+func writeIR(path string, types map[string]*ir.Decl) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer f.Close()
+	if err := ir.Encode(f, types); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func usage() {
+	fmt.Print(`
+	Typewriter
+	Convert Go types to other languages
+	Visit http://www.github.com/natdm/typewriter for more detailed example useage.
+
+	Flags:
+		-dir <dir>
+			Parse a complete directory
+			example: 	-dir= ../src/appname/models/
+			default: 	./
+
+		-file <gofile>
+			Parse a single go file
+			example: 	-file= ../src/appname/models/app.go
+			overrides 	-dir and -recursive
+
+		-out <path>
+			Saves content to folder
+			example: 	-out= ../src/appname/models/
+						-out= ../src/appname/models/customname.js
+			default: 	./models.
+
+		-lang <lang>
+			Language to parse to. One of ["flow", "elm", "ts", "jsdoc"]
+			example:	-lang flow
+			default:	will not parse
+
+			Repeat -lang and -out to write several outputs from one parse.
+			The nth -lang is saved to the nth -out.
+			example:	-lang flow -out ./models.js -lang ts -out ./models.ts
+
+		-templates [<name>=]<dir>
+			Directory of template fragments (header.tmpl, declaration.tmpl,
+			structOpen.tmpl, fieldName.tmpl, ...) and an optional
+			conversions.json, registered as the language <name>, or named
+			after the directory. Drawn instead of -lang, or as one of them
+			when -lang names it
+			example:	-templates kotlin=./typewriter/templates
+
+		-emit-ir <path>
+			Saves the parsed types as versioned JSON, including comments,
+			tags and source positions. Without -lang, nothing else is drawn.
+			example:	-emit-ir ./types.json
+
+		-from-ir <path>
+			Draws the types of a JSON file saved with -emit-ir instead of
+			parsing Go. Overrides -dir and -file. The type filters, -root and
+			-marked-only still pick the types drawn
+			example:	-from-ir ./types.json -lang ts
+
+		-config <path>
+			Project file to read instead of typewriter.yaml, typewriter.yml or
+			.typewriter.json in the working directory. Flags override it
+			example:	-config ./api/typewriter.yaml
+
+		-check
+			Draws every target to memory and compares it with its -out file,
+			printing a unified diff and exiting non-zero when they differ, or
+			when -split would remove a file no longer drawn. Nothing is written
+			example:	-lang flow -out ./models.js -check
+
+		-watch
+			Keeps running, and draws the types again every time a parsed file is
+			saved, added or removed. Only files that changed are parsed again, and
+			only outputs whose content changed are rewritten. Needs -out
+			example:	-lang flow -out ./models.js -watch
+
+		-include <glob>, -exclude <glob>
+			Only parse the Go files matching an -include glob, and skip the
+			files and directories matching an -exclude glob, without walking
+			them. Globs are relative to -dir, '**' matches any number of
+			directories and globs without a slash match base names too.
+			Both can be repeated
+			example:	-exclude vendor -exclude '**/mocks/**' -include 'api/**'
+
+		-tags <tags>, -goos <os>, -goarch <arch>
+			Only parse the Go files that go build would build with these comma
+			separated tags, GOOS and GOARCH, going by //go:build lines and
+			name suffixes such as _windows.go
+			example:	-tags integration,pro -goos windows
+			default:	the host platform, without tags
+
+		-include-types <regexp>, -exclude-types <regexp>
+			Only parse the types whose name matches -include-types, and skip
+			the ones matching -exclude-types
+			example:	-include-types 'Request$|Response$'
+
+		-exported-only
+			Skip unexported types
+			default:	false
+
+		-unexported-fields
+			Draw unexported struct fields too. encoding/json skips them, so
+			they are skipped by default
+			default:	false
+
+		-marked-only
+			Draw only the types marked with an @typewriter comment flag or
+			a //tw:generate directive, and every type they refer to, in any
+			package
+			default:	false
+
+		-root <type>
+			Draw only the named type and every type it refers to through
+			fields, maps, slices and embedded structs, in any package. Can be
+			repeated, and combined with -marked-only
+			example:	-root api.OrderResponse -root UserResponse
+			default:	none
+
+		-collisions <error|prefix>
+			Types of the same name declared in different packages are an
+			error listing where each is declared, or, with prefix, renamed
+			after their package: models.Config becomes ModelsConfig, or,
+			for packages of the same name, their directory: cmd/server's
+			Config becomes ServerConfig
+			default:	error
+
+		-split <package|type>
+			Draws a file per Go package, or per type, into the -out directory
+			instead of a single file, importing the types each file references
+			from the others. Typescript declarations are always exported.
+			Files of the language drawn by typewriter that are no longer drawn,
+			such as the file of a renamed type, are removed, unless another
+			-lang draws them into the same directory
+			example:	-lang ts -out ./src/models -split package
+
+		-index
+			With -split, also draws an index file re-exporting every file.
+			Typescript and Flow only
+			default:	false
+
+		-r
+			Transcends directories
+			default:	true
+
+		-ts-export, -ts-interface, -ts-extends, -ts-readonly
+			Typescript only. Export declarations, declare structs as interfaces,
+			extend embedded types instead of intersecting them, and mark
+			properties as readonly.
+			default:	false
+
+		-flow-exact, -flow-readonly, -flow-covariant
+			Flow only. Make every object exact (types with @inexact get an
+			explicit '...'), wrap objects in $ReadOnly<>, and mark properties
+			as covariant with '+'.
+			default:	false
+
+		-enum-unions
+			Draw types with declared constants, such as 'type Color string'
+			with 'const Red Color = "red"', as a union of their values:
+			"red" | "green". Typescript, Flow and JSDoc only
+			default:	false
+
+		-ts-namespace <name>, -ts-module <name>
+			Typescript only. Wrap all declarations in a 'declare namespace <name>'
+			or 'declare module "<name>"' block, for use as a .d.ts file.
+			example:	-ts-namespace Api -out ./types/api.d.ts
+			default:	none
+
+		-diagnostics <text|json>
+			Every type, field or constant that is skipped is printed to standard
+			error with its position, severity and reason. text prints warnings
+			and errors like a compiler does, e.g.
+			'models/user.go:12:6: warning: Hub: not a supported type', and info
+			too with -v. json prints all of them as a JSON array, for editors,
+			and only errors are logged besides it
+			default:	text
+
+		-diagnostics-out <file>
+			Write the diagnostics to file instead of standard error, which
+			keeps them apart from the logs. The file is replaced every run
+			example:	-diagnostics json -diagnostics-out diagnostics.json
+			default:	none
+
+		-Werror
+			Fail on warnings as well as errors, without writing any output
+			default:	false
+
+		-v
+			Verbose logging, detailing every skipped type, file, or field.
+			default: 	false
+`)
+}
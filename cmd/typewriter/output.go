@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/natdm/typewriter/template"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// sink is where a target is drawn.
+type sink interface {
+	// files returns the drawn files, and the files drawn before that are no
+	// longer drawn.
+	files() ([]*output, error)
+
+	// reset forgets everything drawn, before drawing again.
+	reset()
+}
+
+// output is a target drawn to memory, so nothing is written until every
+// target is drawn, and unchanged files are left alone.
+type output struct {
+	// path is the file to write. Empty writes to standard output.
+	path string
+	buf  bytes.Buffer
+
+	// stale is set for a file that is no longer drawn, which write removes.
+	stale bool
+}
+
+func (o *output) files() ([]*output, error) { return []*output{o}, nil }
+
+func (o *output) reset() { o.buf.Reset() }
+
+// current returns the content of the output's file, empty when it does not exist.
+func (o *output) current() ([]byte, error) {
+	bs, err := ioutil.ReadFile(o.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return bs, err
+}
+
+// write saves the output and reports whether its file changed.
+func (o *output) write() (bool, error) {
+	if o.path == "" {
+		_, err := os.Stdout.Write(o.buf.Bytes())
+		return true, err
+	}
+	bs, err := o.current()
+	if err != nil {
+		return false, err
+	}
+	if o.stale {
+		return true, os.Remove(o.path)
+	}
+	if bytes.Equal(bs, o.buf.Bytes()) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(o.path), 0755); err != nil {
+		return false, err
+	}
+	return true, ioutil.WriteFile(o.path, o.buf.Bytes(), 0644)
+}
+
+// diff returns a unified diff from the output's file to the drawn output,
+// empty when they match.
+func (o *output) diff() (string, error) {
+	bs, err := o.current()
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(bs, o.buf.Bytes()) && !o.stale {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(bs)),
+		B:        difflib.SplitLines(o.buf.String()),
+		FromFile: o.path,
+		ToFile:   o.path + " (generated)",
+		Context:  3,
+	})
+}
+
+// outputDir is a directory a split target is drawn into.
+type outputDir struct {
+	path  string
+	drawn []*output
+
+	// ext and header are the extension and header of the target's files, so
+	// its files no longer drawn can be told apart from other files.
+	ext    string
+	header []byte
+}
+
+// newOutputDir returns the directory at path lang is split into with opts.
+func newOutputDir(path string, lang template.Language, opts template.Options) (*outputDir, error) {
+	ext, err := template.FileExt(lang, opts)
+	if err != nil {
+		return nil, err
+	}
+	var header bytes.Buffer
+	if err := template.Header(&header, lang, opts); err != nil {
+		return nil, err
+	}
+	return &outputDir{path: path, ext: ext, header: header.Bytes()}, nil
+}
+
+// file returns the writer of a file drawn into the directory.
+func (d *outputDir) file(name string) (io.Writer, error) {
+	o := &output{path: filepath.Join(d.path, name)}
+	d.drawn = append(d.drawn, o)
+	return &o.buf, nil
+}
+
+// files returns the drawn files, and the files of the directory the target
+// drew before that are not drawn anymore, such as the file of a renamed type:
+// the files with the target's extension, starting with its header.
+func (d *outputDir) files() ([]*output, error) {
+	fs, err := ioutil.ReadDir(d.path)
+	if os.IsNotExist(err) {
+		return d.drawn, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	drawn := make(map[string]bool)
+	for _, o := range d.drawn {
+		drawn[o.path] = true
+	}
+	files := d.drawn
+	for _, f := range fs {
+		path := filepath.Join(d.path, f.Name())
+		if f.IsDir() || drawn[path] || len(d.header) == 0 || filepath.Ext(path) != d.ext {
+			continue
+		}
+		bs, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(bs, d.header) {
+			files = append(files, &output{path: path, stale: true})
+		}
+	}
+	return files, nil
+}
+
+func (d *outputDir) reset() { d.drawn = nil }
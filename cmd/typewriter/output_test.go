@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/natdm/typewriter/template"
+	"github.com/stretchr/testify/suite"
+)
+
+type OutputTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestOutputTestSuite(t *testing.T) {
+	suite.Run(t, new(OutputTestSuite))
+}
+
+func (s *OutputTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "typewriter")
+	s.Require().NoError(err)
+	s.dir = dir
+}
+
+func (s *OutputTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *OutputTestSuite) TestWrite() {
+	out := &output{path: filepath.Join(s.dir, "models.js")}
+	out.buf.WriteString("type A = number\n")
+
+	changed, err := out.write()
+	s.Require().NoError(err)
+	s.True(changed)
+
+	changed, err = out.write()
+	s.Require().NoError(err)
+	s.False(changed, "an unchanged file is not rewritten")
+}
+
+func (s *OutputTestSuite) TestDiff() {
+	path := filepath.Join(s.dir, "models.js")
+	out := &output{path: path}
+	out.buf.WriteString("type A = number\ntype B = string\n")
+
+	diff, err := out.diff()
+	s.Require().NoError(err)
+	s.Contains(diff, "+type A = number\n", "a missing file is empty")
+
+	s.Require().NoError(ioutil.WriteFile(path, []byte("type A = number\n"), 0644))
+	diff, err = out.diff()
+	s.Require().NoError(err)
+	s.Contains(diff, "--- "+path+"\n")
+	s.Contains(diff, " type A = number\n+type B = string\n")
+
+	_, err = out.write()
+	s.Require().NoError(err)
+	diff, err = out.diff()
+	s.Require().NoError(err)
+	s.Empty(diff)
+}
+
+// header is the header of Typescript and JSDoc files.
+const header = "// Automatically generated by typewriter. Do not edit.\n// http://www.github.com/natdm/typewriter\n\n"
+
+func (s *OutputTestSuite) TestStaleFiles() {
+	old := filepath.Join(s.dir, "Old.ts")
+	s.Require().NoError(ioutil.WriteFile(old, []byte(header), 0644))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(s.dir, "hand.ts"), []byte("export const a = 1\n"), 0644))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(s.dir, "notes.ts"), []byte("// "+header+"// Not drawn by typewriter\n"), 0644))
+
+	dir, err := newOutputDir(s.dir, template.Typescript, template.Options{})
+	s.Require().NoError(err)
+	w, err := dir.file("New.ts")
+	s.Require().NoError(err)
+	w.Write([]byte(header))
+
+	files, err := dir.files()
+	s.Require().NoError(err)
+	s.Require().Len(files, 2, "files not drawn by typewriter are left alone")
+	s.Equal(old, files[1].path)
+	s.True(files[1].stale)
+
+	diff, err := files[1].diff()
+	s.Require().NoError(err)
+	s.Contains(diff, "-// Automatically generated by typewriter. Do not edit.\n")
+
+	for _, f := range files {
+		_, err := f.write()
+		s.Require().NoError(err)
+	}
+	_, err = os.Stat(old)
+	s.True(os.IsNotExist(err))
+	files, err = dir.files()
+	s.Require().NoError(err)
+	s.Len(files, 1)
+}
+
+func (s *OutputTestSuite) TestSharedDirectory() {
+	// Flow and JSDoc files share their extension, and Typescript and JSDoc their header.
+	draw := func() []*output {
+		var sinks []sink
+		for _, f := range []struct {
+			lang template.Language
+			name string
+		}{{template.Typescript, "models.ts"}, {template.Flow, "models.js"}, {template.JSDoc, "types.js"}} {
+			dir, err := newOutputDir(s.dir, f.lang, template.Options{})
+			s.Require().NoError(err)
+			w, err := dir.file(f.name)
+			s.Require().NoError(err)
+			s.Require().NoError(template.Header(w, f.lang, template.Options{}))
+			sinks = append(sinks, dir)
+		}
+		files, err := drawnFiles(sinks)
+		s.Require().NoError(err)
+		return files
+	}
+
+	for i := 0; i < 2; i++ {
+		files := draw()
+		s.Require().Len(files, 3)
+		for _, f := range files {
+			s.False(f.stale, "%s is drawn by another target", f.path)
+			_, err := f.write()
+			s.Require().NoError(err)
+		}
+	}
+}
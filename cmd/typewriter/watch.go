@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/natdm/typewriter"
+	"github.com/natdm/typewriter/ir"
+	log "github.com/sirupsen/logrus"
+)
+
+// watchInterval is how often watched files are checked for changes. Saves
+// are drawn once no file changed for a whole interval.
+const watchInterval = 500 * time.Millisecond
+
+// watch draws the types every time a parsed file changes, rewriting only the
+// outputs whose content changed, unless diagnostics fail. It runs until the
+// process is stopped.
+func watch(c typewriter.Config, sinks []sink, diags *diagnostics, emitIR string) {
+	log.WithField("interval", watchInterval).Info("Watching for changes")
+	err := typewriter.Watch(context.Background(), c, watchInterval, func(types map[string]*ir.Decl) {
+		c.Types = types
+		for _, s := range sinks {
+			s.reset()
+		}
+		var res typewriter.Result
+		if len(sinks) > 0 {
+			r, err := typewriter.Generate(context.Background(), c)
+			if err != nil {
+				log.WithError(err).Error("error drawing types")
+				return
+			}
+			res = r
+		}
+		if failed := diags.print(); failed > 0 {
+			log.Errorf("%d diagnostics fail the run, nothing was written", failed)
+			return
+		}
+
+		outputs, err := drawnFiles(sinks)
+		if err != nil {
+			log.WithError(err).Error("error listing outputs")
+			return
+		}
+		written := 0
+		for _, out := range outputs {
+			changed, err := out.write()
+			if err != nil {
+				log.WithError(err).WithField("file", out.path).Error("error writing output")
+				continue
+			}
+			if changed {
+				written++
+				log.WithField("file", out.path).Debug("wrote output")
+			}
+		}
+		if emitIR != "" {
+			writeIR(emitIR, types)
+		}
+		log.WithField("output_type_ct", res.Count).WithField("written", written).Info("Done")
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,170 @@
+// Package diag records what typewriter skipped or could not make sense of,
+// with the position in the Go source, for printing like a compiler does or as
+// JSON for editors.
+package diag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/natdm/typewriter/ir"
+)
+
+// Severity is how much a diagnostic matters.
+type Severity int
+
+// severities
+const (
+	// Info is something skipped on purpose, such as a type with the @ignore
+	// flag or an unexported field.
+	Info Severity = iota
+
+	// Warning is something skipped because it is not supported, such as a
+	// chan type, or an embedded type that could not be found.
+	Warning
+
+	// Error is something that failed, such as the package of an embedded
+	// type that does not parse.
+	Error
+)
+
+var severities = []string{"info", "warning", "error"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severities) {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severities[s]
+}
+
+// ParseSeverity returns the severity named "info", "warning" or "error".
+func ParseSeverity(name string) (Severity, error) {
+	for i, v := range severities {
+		if v == name {
+			return Severity(i), nil
+		}
+	}
+	return Info, fmt.Errorf("unknown severity %q, pick one of '%s'", name, strings.Join(severities, "', '"))
+}
+
+// MarshalJSON encodes the severity by name.
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON decodes a severity encoded by name.
+func (s *Severity) UnmarshalJSON(bs []byte) error {
+	var name string
+	if err := json.Unmarshal(bs, &name); err != nil {
+		return err
+	}
+	v, err := ParseSeverity(name)
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// Diagnostic is a type, field or constant that was skipped, and why.
+type Diagnostic struct {
+	Pos      ir.Position `json:"pos"`
+	Severity Severity    `json:"severity"`
+
+	// Type and Field name what was skipped. Field is empty for a whole type.
+	Type  string `json:"type,omitempty"`
+	Field string `json:"field,omitempty"`
+
+	Reason string `json:"reason"`
+}
+
+// String formats the diagnostic like a compiler does, e.g.
+// "models/user.go:12:2: info: User.password: unexported field".
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.Pos.File != "" {
+		b.WriteString(d.Pos.String())
+		b.WriteString(": ")
+	}
+	b.WriteString(d.Severity.String())
+	b.WriteString(": ")
+	if d.Type != "" {
+		b.WriteString(d.Type)
+		if d.Field != "" {
+			b.WriteString(".")
+			b.WriteString(d.Field)
+		}
+		b.WriteString(": ")
+	}
+	b.WriteString(d.Reason)
+	return b.String()
+}
+
+// List collects diagnostics. It is safe for concurrent use, and the zero value
+// is an empty list.
+type List struct {
+	mu    sync.Mutex
+	diags []Diagnostic
+}
+
+// Report adds a diagnostic to the list.
+func (l *List) Report(d Diagnostic) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.diags = append(l.diags, d)
+}
+
+// Reset empties the list.
+func (l *List) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.diags = nil
+}
+
+// Diagnostics returns the reported diagnostics of at least severity min,
+// sorted by position.
+func (l *List) Diagnostics(min Severity) []Diagnostic {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var diags []Diagnostic
+	for _, d := range l.diags {
+		if d.Severity >= min {
+			diags = append(diags, d)
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Pos, diags[j].Pos
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return diags
+}
+
+// Print writes diagnostics one per line, like a compiler does.
+func Print(w io.Writer, diags []Diagnostic) error {
+	for _, d := range diags {
+		if _, err := fmt.Fprintln(w, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes diagnostics as a JSON array.
+func WriteJSON(w io.Writer, diags []Diagnostic) error {
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(diags)
+}
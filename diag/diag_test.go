@@ -0,0 +1,65 @@
+package diag
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/natdm/typewriter/ir"
+	"github.com/stretchr/testify/suite"
+)
+
+type DiagTestSuite struct {
+	suite.Suite
+}
+
+func TestDiagTestSuite(t *testing.T) {
+	suite.Run(t, new(DiagTestSuite))
+}
+
+func (s *DiagTestSuite) TestString() {
+	d := Diagnostic{
+		Pos:      ir.Position{File: "models/user.go", Line: 12, Column: 2},
+		Severity: Info,
+		Type:     "User",
+		Field:    "password",
+		Reason:   "unexported field",
+	}
+	s.Equal("models/user.go:12:2: info: User.password: unexported field", d.String())
+	s.Equal("warning: something", Diagnostic{Severity: Warning, Reason: "something"}.String())
+}
+
+func (s *DiagTestSuite) TestList() {
+	var l List
+	l.Report(Diagnostic{Pos: ir.Position{File: "b.go", Line: 1}, Severity: Error, Reason: "b"})
+	l.Report(Diagnostic{Pos: ir.Position{File: "a.go", Line: 9}, Severity: Warning, Reason: "a9"})
+	l.Report(Diagnostic{Pos: ir.Position{File: "a.go", Line: 2}, Severity: Info, Reason: "a2"})
+
+	var reasons []string
+	for _, d := range l.Diagnostics(Warning) {
+		reasons = append(reasons, d.Reason)
+	}
+	s.Equal([]string{"a9", "b"}, reasons)
+	s.Len(l.Diagnostics(Info), 3)
+
+	l.Reset()
+	s.Empty(l.Diagnostics(Info))
+}
+
+func (s *DiagTestSuite) TestJSON() {
+	buf := bytes.Buffer{}
+	s.Require().NoError(WriteJSON(&buf, nil))
+	s.Equal("[]\n", buf.String())
+
+	diags := []Diagnostic{{Pos: ir.Position{File: "a.go", Line: 1, Column: 6}, Severity: Warning, Type: "Hub", Reason: "not a supported type"}}
+	buf.Reset()
+	s.Require().NoError(WriteJSON(&buf, diags))
+	s.Contains(buf.String(), `"severity": "warning"`)
+
+	var decoded []Diagnostic
+	s.Require().NoError(json.Unmarshal(buf.Bytes(), &decoded))
+	s.Equal(diags, decoded)
+
+	var sev Severity
+	s.Error(json.Unmarshal([]byte(`"fatal"`), &sev))
+}
@@ -0,0 +1,54 @@
+package typewriter
+
+import (
+	"path"
+	"strings"
+)
+
+// This file contains the globs selecting the files to parse.
+
+// matchGlob reports whether a slash separated path matches a glob. A "**"
+// segment matches any number of directories, and a glob without a slash
+// matches the base name too, so "mocks" excludes every directory of that name.
+func matchGlob(glob, name string) (bool, error) {
+	if !strings.Contains(glob, "/") {
+		ok, err := path.Match(glob, path.Base(name))
+		if ok || err != nil {
+			return ok, err
+		}
+	}
+	return matchSegments(strings.Split(glob, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(glob, segs []string) (bool, error) {
+	for len(glob) > 0 {
+		if glob[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				ok, err := matchSegments(glob[1:], segs[i:])
+				if ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(segs) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(glob[0], segs[0])
+		if !ok || err != nil {
+			return false, err
+		}
+		glob, segs = glob[1:], segs[1:]
+	}
+	return len(segs) == 0, nil
+}
+
+// matchDir reports whether a directory is matched by a glob, either itself or
+// as "dir/**", which matches everything inside it.
+func matchDir(glob, dir string) (bool, error) {
+	ok, err := matchGlob(glob, dir)
+	if ok || err != nil || !strings.HasSuffix(glob, "/**") {
+		return ok, err
+	}
+	return matchGlob(strings.TrimSuffix(glob, "/**"), dir)
+}
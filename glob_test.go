@@ -0,0 +1,44 @@
+package typewriter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type GlobTestSuite struct {
+	suite.Suite
+}
+
+func TestGlobTestSuite(t *testing.T) {
+	suite.Run(t, new(GlobTestSuite))
+}
+
+func (s *GlobTestSuite) TestMatchGlob() {
+	for _, c := range []struct {
+		glob, path string
+		match      bool
+	}{
+		{"*_gen.go", "api/user_gen.go", true},
+		{"*.go", "api/user.go", true},
+		{"api/*.go", "api/user.go", true},
+		{"api/*.go", "api/v1/user.go", false},
+		{"api/**", "api/v1/user.go", true},
+		{"**/mocks/**", "a/b/mocks/user.go", true},
+		{"**/mocks/**", "mocks/user.go", true},
+		{"**/mocks/**", "a/mocksy/user.go", false},
+		{"vendor", "vendor", true},
+		{"vendor", "api/vendor", true},
+	} {
+		ok, err := matchGlob(c.glob, c.path)
+		s.NoError(err)
+		s.Equal(c.match, ok, "%s %s", c.glob, c.path)
+	}
+
+	ok, err := matchDir("api/**", "api")
+	s.NoError(err)
+	s.True(ok)
+
+	_, err = matchGlob("[", "api")
+	s.Error(err)
+}
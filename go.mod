@@ -13,9 +13,11 @@ require (
 	github.com/gofrs/uuid v3.3.0+incompatible // indirect
 	github.com/jinzhu/gorm v1.9.12
 	github.com/jmhodges/levigo v1.0.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/ponzu-cms/ponzu v0.11.0
 	github.com/remyoudompheng/bigfft v0.0.0-20200410134404-eec4a21b6bb0 // indirect
 	github.com/sirupsen/logrus v1.6.0
 	github.com/stretchr/testify v1.4.0
 	github.com/tecbot/gorocksdb v0.0.0-20191217155057-f0fad39f321c // indirect
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -0,0 +1,170 @@
+// Package ir is the typed model of the Go types typewriter converts.
+//
+// Parsers produce the model and backends consume it. The model is plain data:
+// backends must treat it as read-only, so drawing the same model twice, or in
+// two languages, gives the same results.
+package ir
+
+import "fmt"
+
+// custom type names, for types without a counterpart in other languages
+const (
+	// EmptyInterface is the name of interface{}, the closest to "any".
+	EmptyInterface = "emptyIface"
+
+	// NestedStruct is the name of an anonymous struct.
+	NestedStruct = "struct"
+)
+
+// Kind is the kind of a Type
+type Kind int
+
+// kinds
+const (
+	// Basic is a builtin or named type, referenced by name.
+	Basic Kind = iota
+
+	// Array is a slice or array.
+	Array
+
+	// Map is a map.
+	Map
+
+	// Struct is a struct with fields.
+	Struct
+
+	// Enum is a basic type with a set of declared constants.
+	Enum
+
+	// Union is an interface, holding one of its declared implementations.
+	Union
+)
+
+// Decl is a package level type declaration.
+type Decl struct {
+	Name string `json:"name"`
+
+	// Package is the name of the package declaring the type.
+	Package string `json:"package,omitempty"`
+
+	// Doc is the comment documenting the declaration.
+	Doc string `json:"doc,omitempty"`
+
+	// TypeParams are the type parameters of a generic type.
+	TypeParams []*TypeParam `json:"typeParams,omitempty"`
+
+	Type *Type `json:"type,omitempty"`
+
+	// Strict is set by the @strict comment flag.
+	Strict bool `json:"strict,omitempty"`
+
+	// Inexact is set by the @inexact comment flag.
+	Inexact bool `json:"inexact,omitempty"`
+
+	// Marked is set by the @typewriter comment flag, or a //tw:generate directive.
+	Marked bool `json:"marked,omitempty"`
+
+	Pos Position `json:"pos"`
+}
+
+// TypeParam is a type parameter of a generic type.
+type TypeParam struct {
+	Name string `json:"name"`
+
+	// Constraint is the Go source of the constraint, e.g. "any" or "~int | ~string".
+	Constraint string `json:"constraint,omitempty"`
+}
+
+// Type is a type. Which fields are set depends on its Kind.
+type Type struct {
+	Kind Kind `json:"kind"`
+
+	// Name is the name of a Basic type: a builtin such as "int", a named type
+	// such as "Event" or "pkg.DataType", EmptyInterface or NestedStruct.
+	// For an Enum, it is the name of the underlying builtin type.
+	Name string `json:"name,omitempty"`
+
+	// Args are the type arguments of an instantiated generic Basic type.
+	Args []*Type `json:"args,omitempty"`
+
+	Pointer bool `json:"pointer,omitempty"`
+
+	// Key is the key type of a Map.
+	Key *Type `json:"key,omitempty"`
+
+	// Elem is the element type of an Array, and the value type of a Map.
+	Elem *Type `json:"elem,omitempty"`
+
+	// Fields are the fields of a Struct.
+	Fields []*Field `json:"fields,omitempty"`
+
+	// Embedded are the embedded types of a Struct that were not expanded into Fields.
+	Embedded []string `json:"embedded,omitempty"`
+
+	// EmbeddedPos are the positions of the Embedded types, in the same order.
+	EmbeddedPos []Position `json:"embeddedPos,omitempty"`
+
+	// Values are the declared values of an Enum.
+	Values []*EnumValue `json:"values,omitempty"`
+
+	// Variants are the declared implementations of a Union.
+	Variants []*Variant `json:"variants,omitempty"`
+
+	// Discriminator is the JSON field a Union is told apart by, holding the
+	// Tag of its variant. Without it, variants are told apart by their shape.
+	Discriminator string `json:"discriminator,omitempty"`
+}
+
+// Field is a struct field.
+type Field struct {
+	// Name is the Go name of the field.
+	Name string `json:"name"`
+
+	Type *Type `json:"type,omitempty"`
+
+	// Tag is the raw struct tag, e.g. `json:"name,omitempty"`.
+	Tag string `json:"tag,omitempty"`
+
+	// Doc is the comment above the field.
+	Doc string `json:"doc,omitempty"`
+
+	// Comment is the comment after the field, on the same line.
+	Comment string `json:"comment,omitempty"`
+
+	Pos Position `json:"pos"`
+}
+
+// EnumValue is a constant declared with an Enum type.
+type EnumValue struct {
+	Name string `json:"name"`
+
+	// Value is the constant as encoded in JSON, e.g. `"red"` or `2`.
+	Value string `json:"value"`
+
+	Doc string `json:"doc,omitempty"`
+
+	Pos Position `json:"pos"`
+}
+
+// Variant is a type implementing a Union.
+type Variant struct {
+	// Type is the implementing type, a named Basic type.
+	Type *Type `json:"type"`
+
+	// Tag is the value of the union's Discriminator field for the variant.
+	Tag string `json:"tag,omitempty"`
+}
+
+// Position is a position in a Go source file.
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return "-"
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
@@ -0,0 +1,142 @@
+package ir
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// This file contains the JSON encoding of the model.
+
+// SchemaVersion is the version of the JSON documents written by Encode.
+// It changes whenever the model changes in a way older readers cannot handle.
+const SchemaVersion = 1
+
+// Document is the JSON document holding a model.
+type Document struct {
+	Version int              `json:"version"`
+	Types   map[string]*Decl `json:"types"`
+}
+
+var kindNames = map[Kind]string{
+	Basic:  "basic",
+	Array:  "array",
+	Map:    "map",
+	Struct: "struct",
+	Enum:   "enum",
+	Union:  "union",
+}
+
+func (k Kind) String() string {
+	if n, ok := kindNames[k]; ok {
+		return n
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// MarshalText encodes a kind by its name.
+func (k Kind) MarshalText() ([]byte, error) {
+	n, ok := kindNames[k]
+	if !ok {
+		return nil, fmt.Errorf("unknown kind %d", int(k))
+	}
+	return []byte(n), nil
+}
+
+// UnmarshalText decodes a kind from its name.
+func (k *Kind) UnmarshalText(text []byte) error {
+	for kind, n := range kindNames {
+		if n == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown kind %q", text)
+}
+
+// Encode writes types as an indented JSON Document.
+func Encode(w io.Writer, types map[string]*Decl) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(Document{Version: SchemaVersion, Types: types})
+}
+
+// Decode reads the types of a JSON Document written with the current SchemaVersion.
+// Declarations without a name are named after their key.
+func Decode(r io.Reader) (map[string]*Decl, error) {
+	var doc Document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d, expected %d", doc.Version, SchemaVersion)
+	}
+	if doc.Types == nil {
+		doc.Types = make(map[string]*Decl)
+	}
+	for name, d := range doc.Types {
+		if d == nil || d.Type == nil {
+			return nil, fmt.Errorf("type %q: missing type", name)
+		}
+		if d.Name == "" {
+			d.Name = name
+		}
+		if err := d.Type.validate(); err != nil {
+			return nil, fmt.Errorf("type %q: %v", name, err)
+		}
+	}
+	return doc.Types, nil
+}
+
+// validate checks a decoded type has the fields its kind needs.
+func (t *Type) validate() error {
+	switch t.Kind {
+	case Array:
+		if t.Elem == nil {
+			return fmt.Errorf("array without elem")
+		}
+		return t.Elem.validate()
+	case Map:
+		if t.Key == nil || t.Elem == nil {
+			return fmt.Errorf("map without key or elem")
+		}
+		if err := t.Key.validate(); err != nil {
+			return err
+		}
+		return t.Elem.validate()
+	case Struct:
+		for _, v := range t.Fields {
+			if v == nil || v.Type == nil {
+				return fmt.Errorf("field without type")
+			}
+			if err := v.Type.validate(); err != nil {
+				return fmt.Errorf("field %q: %v", v.Name, err)
+			}
+		}
+	case Union:
+		if len(t.Variants) == 0 {
+			return fmt.Errorf("union without variants")
+		}
+		for _, v := range t.Variants {
+			if v == nil || v.Type == nil {
+				return fmt.Errorf("variant without type")
+			}
+			if err := v.Type.validate(); err != nil {
+				return err
+			}
+		}
+	case Basic, Enum:
+		if t.Name == "" {
+			return fmt.Errorf("%s without name", t.Kind)
+		}
+		for _, v := range t.Args {
+			if v == nil {
+				return fmt.Errorf("nil type argument")
+			}
+			if err := v.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package ir
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type JSONTestSuite struct {
+	suite.Suite
+}
+
+func TestJSONTestSuite(t *testing.T) {
+	suite.Run(t, new(JSONTestSuite))
+}
+
+func (s *JSONTestSuite) TestRoundTrip() {
+	types := map[string]*Decl{
+		"User": {
+			Name:    "User",
+			Package: "models",
+			Doc:     "User is a user\n",
+			Type: &Type{Kind: Struct, Fields: []*Field{
+				{
+					Name:    "Tags",
+					Type:    &Type{Kind: Map, Key: &Type{Kind: Basic, Name: "string"}, Elem: &Type{Kind: Array, Elem: &Type{Kind: Basic, Name: "int", Pointer: true}}},
+					Tag:     `json:"tags,omitempty"`,
+					Comment: "tags",
+					Pos:     Position{File: "models/user.go", Line: 4, Column: 2},
+				},
+			}},
+			Strict: true,
+			Pos:    Position{File: "models/user.go", Line: 3, Column: 6},
+		},
+		"Color": {
+			Name: "Color",
+			Type: &Type{Kind: Enum, Name: "string", Values: []*EnumValue{{Name: "Red", Value: `"red"`}}},
+		},
+		"Shape": {
+			Name: "Shape",
+			Type: &Type{Kind: Union, Discriminator: "kind", Variants: []*Variant{{Type: &Type{Kind: Basic, Name: "Circle"}, Tag: "circle"}}},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	s.Require().NoError(Encode(buf, types))
+	s.Contains(buf.String(), `"version": 1`)
+	s.Contains(buf.String(), `"kind": "enum"`)
+	s.Contains(buf.String(), `"kind": "union"`)
+
+	decoded, err := Decode(buf)
+	s.Require().NoError(err)
+	s.Equal(types, decoded)
+}
+
+func (s *JSONTestSuite) TestDecodeNamesFromKeys() {
+	types, err := Decode(strings.NewReader(`{"version": 1, "types": {"Id": {"type": {"kind": "basic", "name": "int"}}}}`))
+	s.Require().NoError(err)
+	s.Equal("Id", types["Id"].Name)
+}
+
+func (s *JSONTestSuite) TestDecodeErrors() {
+	for _, doc := range []string{
+		`{"version": 2, "types": {}}`,
+		`{"version": 1, "types": {"Id": {"name": "Id"}}}`,
+		`{"version": 1, "types": {"Id": {"type": {"kind": "tuple"}}}}`,
+		`{"version": 1, "types": {"Ids": {"type": {"kind": "array"}}}}`,
+		`{"version": 1, "types": {"Id": {"type": {"kind": "basic"}}}}`,
+		`{"version": 1, "types": {"Shape": {"type": {"kind": "union"}}}}`,
+	} {
+		_, err := Decode(strings.NewReader(doc))
+		s.Error(err, doc)
+	}
+}
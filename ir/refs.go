@@ -0,0 +1,78 @@
+package ir
+
+import "strings"
+
+// References returns the names of the types a declaration refers to through
+// fields, maps, slices, type arguments, embedded types and union variants, as
+// written in Go: "User", or package qualified, "models.User". Type parameters
+// are left out.
+func (d *Decl) References() []string {
+	params := make(map[string]bool)
+	for _, v := range d.TypeParams {
+		params[v.Name] = true
+	}
+
+	var refs []string
+	var walk func(t *Type)
+	walk = func(t *Type) {
+		if t == nil {
+			return
+		}
+		if t.Kind == Basic && !params[t.Name] {
+			refs = append(refs, t.Name)
+		}
+		for _, v := range t.Args {
+			walk(v)
+		}
+		walk(t.Key)
+		walk(t.Elem)
+		for _, v := range t.Fields {
+			walk(v.Type)
+		}
+		for _, v := range t.Variants {
+			walk(v.Type)
+		}
+		for _, v := range t.Embedded {
+			refs = append(refs, strings.TrimSpace(v))
+		}
+	}
+	walk(d.Type)
+	return refs
+}
+
+// Resolve returns the name of the declaration a reference, such as "User" or
+// "models.User", refers to. Qualified references resolve by package name.
+func Resolve(types map[string]*Decl, ref string) (string, bool) {
+	if _, ok := types[ref]; ok {
+		return ref, true
+	}
+	i := strings.LastIndex(ref, ".")
+	if i < 0 {
+		return "", false
+	}
+	name := ref[i+1:]
+	if d, ok := types[name]; ok && d.Package == ref[:i] {
+		return name, true
+	}
+	return "", false
+}
+
+// Reachable returns the declarations roots refer to, directly or not, along
+// with the roots. resolve returns the declarations a reference of d, as
+// returned by References, refers to.
+func Reachable(roots []*Decl, resolve func(d *Decl, ref string) []*Decl) map[*Decl]bool {
+	reached := make(map[*Decl]bool)
+	queue := append([]*Decl(nil), roots...)
+	for len(queue) > 0 {
+		d := queue[0]
+		queue = queue[1:]
+		if reached[d] {
+			continue
+		}
+		reached[d] = true
+		for _, ref := range d.References() {
+			queue = append(queue, resolve(d, ref)...)
+		}
+	}
+	return reached
+}
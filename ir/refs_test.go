@@ -0,0 +1,70 @@
+package ir
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RefsTestSuite struct {
+	suite.Suite
+}
+
+func TestRefsTestSuite(t *testing.T) {
+	suite.Run(t, new(RefsTestSuite))
+}
+
+func (s *RefsTestSuite) types() map[string]*Decl {
+	basic := func(name string) *Type { return &Type{Kind: Basic, Name: name} }
+	return map[string]*Decl{
+		"Order": {Name: "Order", Package: "api", Type: &Type{Kind: Struct, Embedded: []string{"Base"}, Fields: []*Field{
+			{Name: "Items", Type: &Type{Kind: Array, Elem: basic("models.Item")}},
+			{Name: "Page", Type: &Type{Kind: Basic, Name: "Page", Args: []*Type{basic("Item")}}},
+		}}},
+		"Base": {Name: "Base", Package: "api", Type: &Type{Kind: Struct}},
+		"Item": {Name: "Item", Package: "models", Type: &Type{Kind: Struct, Fields: []*Field{
+			{Name: "Tags", Type: &Type{Kind: Map, Key: basic("string"), Elem: basic("Tag")}},
+		}}},
+		"Tag": {Name: "Tag", Package: "models", Type: basic("string")},
+		"Page": {Name: "Page", Package: "api", TypeParams: []*TypeParam{{Name: "T"}}, Type: &Type{Kind: Struct, Fields: []*Field{
+			{Name: "Data", Type: &Type{Kind: Array, Elem: basic("T")}},
+		}}},
+		"Unused": {Name: "Unused", Package: "api", Type: basic("int")},
+	}
+}
+
+func (s *RefsTestSuite) TestReferences() {
+	basic := func(name string) *Type { return &Type{Kind: Basic, Name: name} }
+	types := s.types()
+	s.Equal([]string{"models.Item", "Page", "Item", "Base"}, types["Order"].References())
+	s.Empty(types["Page"].References())
+
+	shape := &Decl{Name: "Shape", Type: &Type{Kind: Union, Variants: []*Variant{{Type: basic("Circle")}, {Type: basic("shapes.Square")}}}}
+	s.Equal([]string{"Circle", "shapes.Square"}, shape.References())
+}
+
+func (s *RefsTestSuite) TestResolve() {
+	types := s.types()
+	name, ok := Resolve(types, "models.Item")
+	s.True(ok)
+	s.Equal("Item", name)
+	_, ok = Resolve(types, "other.Item")
+	s.False(ok)
+	_, ok = Resolve(types, "time.Time")
+	s.False(ok)
+}
+
+func (s *RefsTestSuite) TestReachable() {
+	types := s.types()
+	reached := Reachable([]*Decl{types["Order"]}, func(_ *Decl, ref string) []*Decl {
+		if name, ok := Resolve(types, ref); ok {
+			return []*Decl{types[name]}
+		}
+		return nil
+	})
+	var names []string
+	for d := range reached {
+		names = append(names, d.Name)
+	}
+	s.ElementsMatch([]string{"Order", "Base", "Item", "Tag", "Page"}, names)
+}
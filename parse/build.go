@@ -0,0 +1,50 @@
+package parse
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+)
+
+// This file contains the selection of Go files by build constraints, and the
+// lookup of imported packages.
+
+// Constraints returns a skip function for DirectoryFunc, skipping the Go files
+// ctx excludes by their //go:build or // +build lines, or by their GOOS and
+// GOARCH name suffixes, such as _windows.go, the same way go build does. A nil
+// ctx is build.Default, the host platform without extra tags.
+func Constraints(ctx *build.Context) func(path string, dir bool) (bool, error) {
+	if ctx == nil {
+		ctx = &build.Default
+	}
+	return func(path string, dir bool) (bool, error) {
+		if dir {
+			return false, nil
+		}
+		ok, err := ctx.MatchFile(filepath.Dir(path), filepath.Base(path))
+		return !ok, err
+	}
+}
+
+// importDir returns the directory of the package imported by path from a file
+// in srcDir, looked up like go build does: in GOROOT, in the module of srcDir
+// or in GOPATH. A nil ctx is build.Default.
+func importDir(ctx *build.Context, path, srcDir string) (string, error) {
+	if ctx == nil {
+		ctx = &build.Default
+	}
+	pkg, err := ctx.Import(path, srcDir, build.FindOnly)
+	if err == nil {
+		return pkg.Dir, nil
+	}
+	// GOPATH may have changed since ctx was made.
+	if gopath := os.Getenv("GOPATH"); gopath != "" {
+		for _, root := range filepath.SplitList(gopath) {
+			dir := filepath.Join(root, "src", filepath.FromSlash(path))
+			if fi, statErr := os.Stat(dir); statErr == nil && fi.IsDir() {
+				return dir, nil
+			}
+		}
+	}
+	return "", err
+}
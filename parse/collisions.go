@@ -0,0 +1,192 @@
+package parse
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/natdm/typewriter/ir"
+)
+
+// This file contains the handling of types of the same name declared in different packages.
+
+// Collisions is how types of the same name, declared in different packages, are handled.
+type Collisions int
+
+// collision strategies
+const (
+	// CollisionsError fails parsing, listing where every colliding type is declared.
+	CollisionsError Collisions = iota
+
+	// CollisionsPrefix prefixes every colliding type with the name of its
+	// package, e.g. models.Config becomes ModelsConfig, and renames every
+	// reference to it. Packages of the same name, such as main packages, are
+	// told apart by their directory: cmd/server's Config becomes ServerConfig.
+	CollisionsPrefix
+)
+
+// ParseCollisions returns the strategy named "error" or "prefix". An empty name is CollisionsError.
+func ParseCollisions(name string) (Collisions, error) {
+	switch name {
+	case "", "error":
+		return CollisionsError, nil
+	case "prefix":
+		return CollisionsPrefix, nil
+	}
+	return CollisionsError, fmt.Errorf("unknown collision strategy %q, pick one of 'error' and 'prefix'", name)
+}
+
+// CollisionError is a type name declared more than once.
+type CollisionError struct {
+	Name  string
+	Decls []*ir.Decl
+}
+
+func (e *CollisionError) Error() string {
+	at := make([]string, 0, len(e.Decls))
+	for _, d := range e.Decls {
+		at = append(at, fmt.Sprintf("%s (package %s)", d.Pos, d.Package))
+	}
+	return fmt.Sprintf("%s is declared more than once: %s", e.Name, strings.Join(at, ", "))
+}
+
+// resolveCollisions returns the declarations by name. Names declared more than
+// once are an error, or prefixed with their package name, or the name of their
+// directory for packages of the same name. The declarations must be copies, as
+// renamed ones are changed.
+func resolveCollisions(byName map[string][]*ir.Decl, collisions Collisions) (map[string]*ir.Decl, error) {
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	typs := make(map[string]*ir.Decl, len(byName))
+	var colliding []string
+	for _, name := range names {
+		if decls := byName[name]; len(decls) == 1 {
+			typs[name] = decls[0]
+			continue
+		}
+		if collisions == CollisionsError {
+			return nil, &CollisionError{Name: name, Decls: byName[name]}
+		}
+		colliding = append(colliding, name)
+	}
+	if len(colliding) == 0 {
+		return typs, nil
+	}
+
+	// local maps the types renamed in every package, by directory, to their
+	// new name, and qualified the ones other packages can refer to as pkg.Name.
+	local := make(map[scoped]string)
+	qualified := make(map[string]string)
+	for _, name := range colliding {
+		packages := make(map[string]int)
+		for _, d := range byName[name] {
+			packages[d.Package]++
+		}
+		for _, d := range byName[name] {
+			prefix := title(d.Package)
+			if packages[d.Package] > 1 {
+				// Packages of the same name, such as main packages, are told
+				// apart by their directory, and cannot refer to one another.
+				prefix = identifier(filepath.Base(packageDir(d)))
+			} else {
+				qualified[d.Package+"."+name] = prefix + name
+			}
+			prefixed := prefix + name
+			if other, ok := typs[prefixed]; ok {
+				return nil, &CollisionError{Name: prefixed, Decls: []*ir.Decl{other, d}}
+			}
+			local[scoped{packageDir(d), name}] = prefixed
+			d.Name = prefixed
+			typs[prefixed] = d
+		}
+	}
+	for _, d := range typs {
+		d := d
+		d.Type = renameType(d.Type, func(name string) string {
+			to, ok := local[scoped{packageDir(d), name}]
+			if strings.Contains(name, ".") {
+				to, ok = qualified[name]
+			}
+			if ok {
+				return to
+			}
+			return name
+		})
+	}
+	return typs, nil
+}
+
+// scoped is a type name within the package declared in a directory.
+type scoped struct {
+	dir, name string
+}
+
+// packageDir returns the directory of the package d is declared in, or its package
+// name for declarations without a position.
+func packageDir(d *ir.Decl) string {
+	if d.Pos.File == "" {
+		return d.Package
+	}
+	return filepath.Dir(d.Pos.File)
+}
+
+// renameType returns a copy of t referring to types by the name rename returns.
+func renameType(t *ir.Type, rename func(string) string) *ir.Type {
+	if t == nil {
+		return nil
+	}
+
+	c := *t
+	if c.Kind == ir.Basic {
+		c.Name = rename(c.Name)
+	}
+	c.Key = renameType(t.Key, rename)
+	c.Elem = renameType(t.Elem, rename)
+	c.Args = nil
+	for _, v := range t.Args {
+		c.Args = append(c.Args, renameType(v, rename))
+	}
+	c.Fields = nil
+	for _, v := range t.Fields {
+		f := *v
+		f.Type = renameType(v.Type, rename)
+		c.Fields = append(c.Fields, &f)
+	}
+	c.Variants = nil
+	for _, v := range t.Variants {
+		variant := *v
+		variant.Type = renameType(v.Type, rename)
+		c.Variants = append(c.Variants, &variant)
+	}
+	c.Embedded = nil
+	for _, v := range t.Embedded {
+		c.Embedded = append(c.Embedded, rename(strings.TrimSpace(v)))
+	}
+	return &c
+}
+
+// identifier returns s as an identifier, titling every run of letters and
+// digits: "my-tool" becomes MyTool.
+func identifier(s string) string {
+	var b strings.Builder
+	for _, w := range strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		b.WriteString(title(w))
+	}
+	return b.String()
+}
+
+// title uppercases the first letter of s.
+func title(s string) string {
+	if s == "" {
+		return s
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
@@ -0,0 +1,31 @@
+package parse
+
+import (
+	"github.com/natdm/typewriter/diag"
+	log "github.com/sirupsen/logrus"
+)
+
+// report records a diagnostic in diags, and logs it at debug level. Without a
+// list, it is logged at the level of its severity instead.
+func report(logger log.FieldLogger, diags *diag.List, d diag.Diagnostic) {
+	entry := logger.WithField("pos", d.Pos.String())
+	if d.Type != "" {
+		entry = entry.WithField("type_name", d.Type)
+	}
+	if d.Field != "" {
+		entry = entry.WithField("field_name", d.Field)
+	}
+	if diags != nil {
+		diags.Report(d)
+		entry.Debug(d.Reason)
+		return
+	}
+	switch d.Severity {
+	case diag.Error:
+		entry.Error(d.Reason)
+	case diag.Warning:
+		entry.Warn(d.Reason)
+	default:
+		entry.Debug(d.Reason)
+	}
+}
@@ -0,0 +1,413 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/natdm/typewriter/diag"
+	"github.com/natdm/typewriter/ir"
+	"github.com/natdm/typewriter/template"
+	log "github.com/sirupsen/logrus"
+)
+
+// This file contains the expansion of embedded structs into their fields, by
+// the rules encoding/json promotes fields with.
+
+// scope is a package embedded types are looked up in.
+type scope struct {
+	types map[string]*ir.Decl
+
+	// imports are the paths of the imported packages, by package name.
+	imports map[string]string
+
+	// dir is the package's directory, its imports are looked up from. It is
+	// empty for the parsed files, whose imports are looked up from the
+	// directory of the struct embedding them.
+	dir string
+}
+
+// expander expands embedded structs, parsing the packages of embedded structs
+// declared outside of the parsed files when they are first embedded.
+type expander struct {
+	local    *scope
+	packages map[string]*scope
+	opts     Options
+	logger   log.FieldLogger
+
+	// reported are the embedded types problems were reported for, so each is
+	// only reported once.
+	reported map[embed]bool
+}
+
+// embed is a type embedded in a struct, as written.
+type embed struct {
+	in  *ir.Decl
+	ref string
+}
+
+// EmbedCycleError is a struct embedding itself, through the chain of embedded
+// structs in Chain, as written in their structs.
+type EmbedCycleError struct {
+	Decl  *ir.Decl
+	Chain []string
+}
+
+func (e *EmbedCycleError) Error() string {
+	return fmt.Sprintf("%s: %s embeds itself: %s -> %s", e.Decl.Pos, e.Decl.Name, e.Decl.Name, strings.Join(e.Chain, " -> "))
+}
+
+// embedding is an embedded struct, found while walking the structs a struct embeds.
+type embedding struct {
+	scope *scope
+	decl  *ir.Decl
+
+	// index is the path of field indexes to the struct.
+	index []int
+}
+
+// promoted is a field of a struct or of a struct it embeds.
+type promoted struct {
+	field *ir.Field
+
+	// name is the field's JSON name, tagged when it is named by its json tag.
+	name   string
+	tagged bool
+
+	// index is the path of field indexes to the field. Embedded structs are
+	// indexed after the fields of the struct embedding them.
+	index []int
+}
+
+// expandEmbeddedTypes replaces the embedded structs of every struct with the
+// fields encoding/json promotes from them: fields are promoted through any
+// number of embedded structs and struct pointers, shallower fields hide deeper
+// ones, and fields of the same name at the same depth hide each other, unless
+// exactly one of them is named by its json tag. Structs embedding themselves,
+// through any number of embedded structs, are an *EmbedCycleError.
+func expandEmbeddedTypes(types map[string]*ir.Decl, pkgs map[string]string, opts Options, logger log.FieldLogger) error {
+	x := &expander{
+		local:    &scope{types: types, imports: pkgs},
+		packages: make(map[string]*scope),
+		opts:     opts,
+		logger:   logger,
+		reported: make(map[embed]bool),
+	}
+
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	done := make(map[*ir.Decl]bool)
+	for _, name := range names {
+		if err := x.cycle(x.local, types[name], nil, nil, done); err != nil {
+			return err
+		}
+	}
+
+	// Every struct is expanded from the types as parsed, before any is changed.
+	expanded := make(map[string][]*ir.Field)
+	for name, d := range types {
+		if d.Type.Kind == ir.Struct && len(d.Type.Embedded) > 0 {
+			expanded[name] = x.fields(d)
+		}
+	}
+	for name, fields := range expanded {
+		types[name].Type.Fields = fields
+		types[name].Type.Embedded = nil
+		types[name].Type.EmbeddedPos = nil
+	}
+	return nil
+}
+
+// cycle walks the structs d embeds depth first, in the order they are
+// embedded, and returns an *EmbedCycleError for the first struct on the stack
+// embedded again. chain are the embedded types from every struct on the stack
+// to the next one. Structs in done embed no cycles.
+func (x *expander) cycle(s *scope, d *ir.Decl, stack []*ir.Decl, chain []string, done map[*ir.Decl]bool) error {
+	for i, v := range stack {
+		if v == d {
+			return &EmbedCycleError{Decl: d, Chain: append([]string(nil), chain[i:]...)}
+		}
+	}
+	if done[d] || d.Type.Kind != ir.Struct {
+		return nil
+	}
+	stack = append(stack, d)
+	for _, ref := range d.Type.Embedded {
+		es, ed, ok := x.lookup(s, d, ref)
+		if !ok {
+			continue
+		}
+		if err := x.cycle(es, ed, stack, append(chain, strings.TrimSpace(ref)), done); err != nil {
+			return err
+		}
+	}
+	done[d] = true
+	return nil
+}
+
+// fields returns the fields of a struct as encoding/json encodes them, in the
+// order they are declared in, with the promoted fields of embedded structs
+// after the struct's own.
+func (x *expander) fields(d *ir.Decl) []*ir.Field {
+	var found []promoted
+	next := []embedding{{scope: x.local, decl: d}}
+	nextCount := map[*ir.Decl]int{d: 1}
+	visited := make(map[*ir.Decl]bool)
+
+	// Embedded structs are walked a depth at a time, like encoding/json does.
+	for len(next) > 0 {
+		current, count := next, nextCount
+		next, nextCount = nil, make(map[*ir.Decl]int)
+
+		for _, e := range current {
+			if visited[e.decl] {
+				continue
+			}
+			visited[e.decl] = true
+
+			t := e.decl.Type
+			for i, f := range t.Fields {
+				name, tagged := jsonName(f)
+				p := promoted{field: f, name: name, tagged: tagged, index: appendIndex(e.index, i)}
+				found = append(found, p)
+				if count[e.decl] > 1 {
+					// A struct embedded more than once at the same depth hides its own
+					// fields. One more copy is enough for them to collide.
+					found = append(found, p)
+				}
+			}
+			for i, ref := range t.Embedded {
+				s, ed, ok := x.lookup(e.scope, e.decl, ref)
+				if !ok {
+					continue
+				}
+				if ed.Type.Kind != ir.Struct {
+					f, ok := x.embeddedField(e.scope, e.decl, ed, ref)
+					if !ok {
+						continue
+					}
+					p := promoted{field: f, name: f.Name, index: appendIndex(e.index, len(t.Fields)+i)}
+					found = append(found, p)
+					if count[e.decl] > 1 {
+						found = append(found, p)
+					}
+					continue
+				}
+				nextCount[ed]++
+				if nextCount[ed] == 1 {
+					next = append(next, embedding{scope: s, decl: ed, index: appendIndex(e.index, len(t.Fields)+i)})
+				}
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].name != found[j].name {
+			return found[i].name < found[j].name
+		}
+		if len(found[i].index) != len(found[j].index) {
+			return len(found[i].index) < len(found[j].index)
+		}
+		if found[i].tagged != found[j].tagged {
+			return found[i].tagged
+		}
+		return lessIndex(found[i].index, found[j].index)
+	})
+
+	// The first field of every name is the shallowest, tagged one. It is only
+	// dominant when the next one of the name is deeper, or untagged.
+	var dominant []promoted
+	for i := 0; i < len(found); {
+		j := i + 1
+		for j < len(found) && found[j].name == found[i].name {
+			j++
+		}
+		if j-i == 1 || len(found[i].index) < len(found[i+1].index) || found[i].tagged != found[i+1].tagged {
+			dominant = append(dominant, found[i])
+		} else {
+			report(x.logger, x.opts.Diagnostics, diag.Diagnostic{
+				Pos:      d.Pos,
+				Severity: diag.Warning,
+				Type:     d.Name,
+				Field:    found[i].name,
+				Reason:   "fields of the same name at the same depth of embedded structs hide each other, skipped",
+			})
+		}
+		i = j
+	}
+
+	sort.Slice(dominant, func(i, j int) bool { return lessIndex(dominant[i].index, dominant[j].index) })
+	fields := make([]*ir.Field, 0, len(dominant))
+	for _, p := range dominant {
+		fields = append(fields, p.field)
+	}
+	return fields
+}
+
+// embeddedField returns the field encoding/json encodes an embedded type that
+// is not a struct as, such as a named map or string: a field named after the
+// type. Unexported types are skipped, like encoding/json does.
+func (x *expander) embeddedField(from *scope, in, d *ir.Decl, ref string) (*ir.Field, bool) {
+	ref = strings.TrimSpace(ref)
+	if !ast.IsExported(d.Name) {
+		x.report(in, ref, diag.Info, "embedded type %s is unexported and not a struct, skipped", ref)
+		return nil, false
+	}
+	name := strings.TrimPrefix(ref, "*")
+	if from != x.local {
+		// Types embedded in structs of other packages are drawn by their qualified name.
+		name = d.Package + "." + d.Name
+	}
+	return &ir.Field{
+		Name: d.Name,
+		Type: &ir.Type{Kind: ir.Basic, Name: name, Pointer: strings.HasPrefix(ref, "*")},
+	}, true
+}
+
+// lookup returns a type embedded in the struct in, such as "Base", "*Base" or
+// "models.Base", and the scope to look up the types it embeds in. Types from
+// other packages are looked up in the parsed types first.
+func (x *expander) lookup(s *scope, in *ir.Decl, ref string) (*scope, *ir.Decl, bool) {
+	ref = strings.TrimPrefix(strings.TrimSpace(ref), "*")
+	if s == x.local {
+		if name, ok := ir.Resolve(s.types, ref); ok {
+			return s, s.types[name], true
+		}
+	} else if d, ok := s.types[ref]; ok {
+		return s, d, true
+	}
+
+	i := strings.LastIndex(ref, ".")
+	if i < 0 {
+		x.report(in, ref, diag.Warning, "embedded type %s could not be found, skipped", ref)
+		return nil, nil, false
+	}
+	path, ok := s.imports[ref[:i]]
+	if !ok {
+		x.report(in, ref, diag.Warning, "package of embedded type %s is not imported, skipped", ref)
+		return nil, nil, false
+	}
+	srcDir := s.dir
+	if srcDir == "" {
+		srcDir = filepath.Dir(in.Pos.File)
+	}
+	dir, err := importDir(x.opts.Build, path, srcDir)
+	if err != nil {
+		x.report(in, ref, diag.Warning, "package of embedded type %s could not be found, skipped: %v", ref, err)
+		return nil, nil, false
+	}
+	pkg := x.parsePackage(in, ref, dir)
+	if pkg == nil {
+		return nil, nil, false
+	}
+	d, ok := pkg.types[ref[i+1:]]
+	if !ok {
+		x.report(in, ref, diag.Warning, "embedded type %s could not be found in its package, skipped", ref)
+		return nil, nil, false
+	}
+	return pkg, d, true
+}
+
+// parsePackage parses the package in dir of the type ref embedded in the
+// struct in, once. Packages that fail to parse are nil.
+func (x *expander) parsePackage(in *ir.Decl, ref, dir string) *scope {
+	if s, ok := x.packages[dir]; ok {
+		return s
+	}
+	x.packages[dir] = nil
+
+	var files []string
+	if err := DirectoryFunc(dir, false, Constraints(x.opts.Build), &files); err != nil {
+		x.report(in, ref, diag.Warning, "package of embedded type %s could not be read, skipped: %v", ref, err)
+		return nil
+	}
+	s := &scope{types: make(map[string]*ir.Decl), imports: make(map[string]string), dir: dir}
+	for _, name := range files {
+		// What other packages skip is not worth more than a debug line.
+		f, err := ParseFile(name, x.logger, Options{UnexportedFields: x.opts.UnexportedFields, Diagnostics: new(diag.List)})
+		if err != nil {
+			x.report(in, ref, diag.Error, "package of embedded type %s failed to parse: %v", ref, err)
+			return nil
+		}
+		for k, v := range f.Types {
+			s.types[k] = v
+		}
+		for k, v := range f.Imports {
+			s.imports[k] = v
+		}
+	}
+	x.packages[dir] = s
+	return s
+}
+
+// report reports a problem with the type ref embedded in the struct in, once,
+// at the position ref is embedded at.
+func (x *expander) report(in *ir.Decl, ref string, severity diag.Severity, format string, args ...interface{}) {
+	key := embed{in, ref}
+	if x.reported[key] {
+		return
+	}
+	x.reported[key] = true
+	report(x.logger, x.opts.Diagnostics, diag.Diagnostic{Pos: embedPos(in, ref), Severity: severity, Type: in.Name, Reason: fmt.Sprintf(format, args...)})
+}
+
+// embedPos returns the position the type ref is embedded at in the struct in,
+// or the struct's position when it is not known, as in types read from JSON.
+func embedPos(in *ir.Decl, ref string) ir.Position {
+	ref = strings.TrimPrefix(strings.TrimSpace(ref), "*")
+	for i, v := range in.Type.Embedded {
+		if strings.TrimPrefix(strings.TrimSpace(v), "*") == ref && i < len(in.Type.EmbeddedPos) {
+			return in.Type.EmbeddedPos[i]
+		}
+	}
+	return in.Pos
+}
+
+// jsonName returns the name encoding/json encodes a field by, and whether it
+// is named by its json tag.
+func jsonName(f *ir.Field) (string, bool) {
+	name := strings.Split(template.GetTag("json", f.Tag), ",")[0]
+	if validTag(name) {
+		return name, true
+	}
+	return f.Name, false
+}
+
+// validTag reports whether encoding/json accepts a json tag name.
+func validTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+// appendIndex returns a copy of index with i appended.
+func appendIndex(index []int, i int) []int {
+	return append(append(make([]int, 0, len(index)+1), index...), i)
+}
+
+// lessIndex orders field index paths.
+func lessIndex(a, b []int) bool {
+	for k, i := range a {
+		if k >= len(b) {
+			return false
+		}
+		if i != b[k] {
+			return i < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
@@ -0,0 +1,145 @@
+package parse
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/natdm/typewriter/diag"
+	"github.com/natdm/typewriter/ir"
+	log "github.com/sirupsen/logrus"
+)
+
+// This file contains the logic for finding enums: constants declared with a named type.
+
+// enumValues returns the constants of a file declared with a named type, by type name.
+// Constants are evaluated as far as literals, iota and arithmetic go; anything
+// else, like references to other constants, is skipped.
+func enumValues(fset *token.FileSet, f *ast.File, logger log.FieldLogger, diags *diag.List) map[string][]*ir.EnumValue {
+	values := make(map[string][]*ir.EnumValue)
+	for _, d := range f.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.CONST {
+			continue
+		}
+
+		// Specs without values repeat the type and values of the previous spec.
+		var (
+			typ   ast.Expr
+			exprs []ast.Expr
+		)
+		for iota, s := range gd.Specs {
+			vs, ok := s.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			if vs.Values != nil {
+				typ, exprs = vs.Type, vs.Values
+			}
+			ident, ok := typ.(*ast.Ident)
+			if !ok {
+				continue
+			}
+
+			for i, n := range vs.Names {
+				if n.Name == "_" || i >= len(exprs) {
+					continue
+				}
+				val, ok := evalConst(exprs[i], iota)
+				if !ok {
+					report(logger, diags, diag.Diagnostic{Pos: position(fset, n.Pos()), Severity: diag.Warning, Type: ident.Name, Reason: "constant " + n.Name + " could not be evaluated, skipped"})
+					continue
+				}
+				v := &ir.EnumValue{
+					Name:  n.Name,
+					Value: jsonConst(val),
+					Pos:   position(fset, n.Pos()),
+				}
+				if vs.Doc != nil {
+					v.Doc = strings.TrimSuffix(vs.Doc.Text(), "\n")
+				} else if vs.Comment != nil {
+					v.Doc = strings.TrimSuffix(vs.Comment.Text(), "\n")
+				}
+				values[ident.Name] = append(values[ident.Name], v)
+			}
+		}
+	}
+	return values
+}
+
+// evalConst evaluates a constant expression.
+func evalConst(exp ast.Expr, iota int) (constant.Value, bool) {
+	switch x := exp.(type) {
+	case *ast.BasicLit:
+		v := constant.MakeFromLiteral(x.Value, x.Kind, 0)
+		return v, v.Kind() != constant.Unknown
+
+	case *ast.Ident:
+		switch x.Name {
+		case "iota":
+			return constant.MakeInt64(int64(iota)), true
+		case "true", "false":
+			return constant.MakeBool(x.Name == "true"), true
+		}
+		return nil, false
+
+	case *ast.ParenExpr:
+		return evalConst(x.X, iota)
+
+	case *ast.UnaryExpr:
+		v, ok := evalConst(x.X, iota)
+		if !ok {
+			return nil, false
+		}
+		return constant.UnaryOp(x.Op, v, 0), true
+
+	case *ast.BinaryExpr:
+		l, ok := evalConst(x.X, iota)
+		if !ok {
+			return nil, false
+		}
+		r, ok := evalConst(x.Y, iota)
+		if !ok {
+			return nil, false
+		}
+		switch x.Op {
+		case token.SHL, token.SHR:
+			s, ok := constant.Uint64Val(r)
+			if !ok {
+				return nil, false
+			}
+			return constant.Shift(l, x.Op, uint(s)), true
+		case token.QUO:
+			if constant.Sign(r) == 0 {
+				return nil, false
+			}
+			if l.Kind() == constant.Int && r.Kind() == constant.Int {
+				return constant.BinaryOp(l, token.QUO_ASSIGN, r), true
+			}
+		}
+		v := constant.BinaryOp(l, x.Op, r)
+		return v, v.Kind() != constant.Unknown
+
+	case *ast.CallExpr:
+		// Conversions, such as Color(1)
+		if len(x.Args) == 1 {
+			return evalConst(x.Args[0], iota)
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// jsonConst returns a constant as encoded in JSON.
+func jsonConst(v constant.Value) string {
+	switch v.Kind() {
+	case constant.String:
+		return strconv.Quote(constant.StringVal(v))
+	case constant.Float:
+		f, _ := constant.Float64Val(v)
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return v.ExactString()
+}
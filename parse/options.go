@@ -0,0 +1,130 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"regexp"
+	"strings"
+
+	"github.com/natdm/typewriter/diag"
+	"github.com/natdm/typewriter/ir"
+	log "github.com/sirupsen/logrus"
+)
+
+// Options change what is parsed. The zero value parses every type, and fails
+// on types of the same name declared in different packages.
+type Options struct {
+	// ExpandEmbedded expands embedded structs into their fields.
+	ExpandEmbedded bool
+
+	// Collisions is how types of the same name in different packages are handled.
+	Collisions Collisions
+
+	// IncludeTypes, when set, parses only the types whose name it matches.
+	IncludeTypes *regexp.Regexp
+
+	// ExcludeTypes skips the types whose name it matches.
+	ExcludeTypes *regexp.Regexp
+
+	// ExportedOnly skips unexported types.
+	ExportedOnly bool
+
+	// UnexportedFields keeps the unexported fields of structs, which
+	// encoding/json skips, for targets not describing JSON.
+	UnexportedFields bool
+
+	// MarkedOnly keeps only the types marked with an @typewriter comment
+	// flag or a //tw:generate directive, and the types they refer to.
+	MarkedOnly bool
+
+	// Roots, when set, keeps only the named types, such as "Response" or
+	// "api.Response", and the types they refer to. With MarkedOnly, the
+	// marked types are roots too. Unknown roots are an error.
+	Roots []string
+
+	// Unions declare the implementations of interfaces, by the name of the
+	// interface, such as "Shape" or "shapes.Shape". They replace the union
+	// declared by the interface's //tw:union directive.
+	Unions map[string]Union
+
+	// Build selects the files of packages parsed for their embedded structs
+	// by build constraints. Defaults to build.Default.
+	Build *build.Context
+
+	// Diagnostics, when set, collects every skipped type, field and constant,
+	// which are then only logged at debug level.
+	Diagnostics *diag.List
+}
+
+// prune returns the root types, and every type they refer to, by name, when
+// any roots are set. Otherwise every type is returned. It runs before
+// collisions are resolved, so types of the same name in other packages are
+// only kept when they are reached too: references resolve within the package
+// of the referring type first, or by package when qualified.
+func (o Options) prune(byName map[string][]*ir.Decl, logger log.FieldLogger) (map[string][]*ir.Decl, error) {
+	if !o.MarkedOnly && len(o.Roots) == 0 {
+		return byName, nil
+	}
+	qualified := make(map[string][]*ir.Decl)
+	for name, decls := range byName {
+		for _, d := range decls {
+			qualified[d.Package+"."+name] = append(qualified[d.Package+"."+name], d)
+		}
+	}
+	// resolve returns the types a reference from package pkg refers to.
+	resolve := func(ref, pkg string) []*ir.Decl {
+		ref = strings.TrimPrefix(ref, "*")
+		if strings.Contains(ref, ".") {
+			return qualified[ref]
+		}
+		if decls, ok := qualified[pkg+"."+ref]; ok {
+			return decls
+		}
+		return byName[ref]
+	}
+
+	var queue []*ir.Decl
+	for _, root := range o.Roots {
+		decls := resolve(root, "")
+		if len(decls) == 0 {
+			return nil, fmt.Errorf("root type %s not found", root)
+		}
+		queue = append(queue, decls...)
+	}
+	if o.MarkedOnly {
+		for _, decls := range byName {
+			for _, d := range decls {
+				if d.Marked {
+					queue = append(queue, d)
+				}
+			}
+		}
+	}
+	reached := ir.Reachable(queue, func(d *ir.Decl, ref string) []*ir.Decl {
+		return resolve(ref, d.Package)
+	})
+
+	kept := make(map[string][]*ir.Decl)
+	for name, decls := range byName {
+		for _, d := range decls {
+			if reached[d] {
+				kept[name] = append(kept[name], d)
+			} else {
+				report(logger, o.Diagnostics, diag.Diagnostic{Pos: d.Pos, Severity: diag.Info, Type: name, Reason: "not referenced by the root or marked types"})
+			}
+		}
+	}
+	return kept, nil
+}
+
+// selects reports whether a type is parsed, going by its name alone.
+func (o Options) selects(name string) bool {
+	if o.ExportedOnly && !ast.IsExported(name) {
+		return false
+	}
+	if o.IncludeTypes != nil && !o.IncludeTypes.MatchString(name) {
+		return false
+	}
+	return o.ExcludeTypes == nil || !o.ExcludeTypes.MatchString(name)
+}
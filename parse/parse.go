@@ -7,17 +7,18 @@ import (
 	"go/parser"
 	"go/token"
 	"io/ioutil"
+	"sort"
+	"strconv"
 	"strings"
 
-	"os"
-
+	"github.com/natdm/typewriter/diag"
+	"github.com/natdm/typewriter/ir"
 	"github.com/natdm/typewriter/template"
 	log "github.com/sirupsen/logrus"
 )
 
 var (
 	errSkipType           = errors.New("not a supported type")
-	errTypeAssert         = errors.New("type assertion failed")
 	errParsingTypeDetails = errors.New("failed to parse type within type")
 	errEmbeddedType       = errors.New("embedded type")
 )
@@ -27,177 +28,297 @@ type commentFlags struct {
 	// strict is for flow types only.
 	strict bool
 
+	// inexact is for flow types only.
+	inexact bool
+
 	// ignore ignores the type from being parsed
 	ignore bool
+
+	// marked opts the type in to generation, see Options.MarkedOnly.
+	marked bool
+
+	// union declares the implementations of an interface, see Options.Unions.
+	union *Union
+
+	// unexported keeps unexported fields. It is set by Options.UnexportedFields
+	// rather than a comment.
+	unexported bool
 }
 
 // Directory parses a directory and returns all the go files that are not test files
 // It takes a directory, a recursive boolean option, and an out to put the files in.
-func Directory(d string, r bool, out *[]string, verbose bool) error {
+// Files excluded by build constraints for the host platform are left out.
+func Directory(d string, r bool, out *[]string) error {
+	return DirectoryFunc(d, r, Constraints(nil), out)
+}
+
+// DirectoryFunc is Directory, skipping every file and directory skip returns
+// true for. Skipped directories are not read at all.
+func DirectoryFunc(d string, r bool, skip func(path string, dir bool) (bool, error), out *[]string) error {
 	fs, err := ioutil.ReadDir(d)
 	if err != nil {
 		return err
 	}
 	for _, v := range fs {
 		name := v.Name()
+		path := strings.Replace(fmt.Sprintf("%s/%s", d, name), "//", "/", -1)
+		if v.IsDir() && !r {
+			continue
+		}
+		if !v.IsDir() && (!strings.HasSuffix(name, "go") || strings.Contains(name, "_test.go")) {
+			continue
+		}
+		if skip != nil {
+			skipped, err := skip(path, v.IsDir())
+			if err != nil {
+				return err
+			}
+			if skipped {
+				continue
+			}
+		}
 		if v.IsDir() {
-			if r {
-				if err := Directory(d+"/"+name, r, out, verbose); err != nil {
-					return err
-				}
+			if err := DirectoryFunc(path, r, skip, out); err != nil {
+				return err
 			}
-		} else if strings.HasSuffix(name, "go") && !strings.Contains(name, "_test.go") {
-			*out = append(*out, strings.Replace(fmt.Sprintf("%s/%s", d, name), "//", "/", -1))
+		} else {
+			*out = append(*out, path)
 		}
 	}
 	return nil
 }
 
-// findImports should keep either the alias name of the import or the package name of an imported package
+// findImports returns the paths of the packages a file imports, by their alias
+// or, when not aliased, the last element of their path.
 func findImports(f *ast.File) map[string]string {
-	r := strings.NewReplacer("\"", "")
 	imports := make(map[string]string)
 	for _, v := range f.Imports {
-		srcpath := os.Getenv("GOPATH") + "/src/"
+		path, err := strconv.Unquote(v.Path.Value)
+		if err != nil {
+			continue
+		}
 		if v.Name != nil {
-			imports[v.Name.String()] = r.Replace(srcpath + v.Path.Value)
+			imports[v.Name.String()] = path
 		} else {
-			_n := strings.Split(v.Path.Value, "/")
-			n := r.Replace(_n[len(_n)-1])
-			imports[n] = r.Replace(srcpath + v.Path.Value)
+			imports[path[strings.LastIndex(path, "/")+1:]] = path
 		}
 	}
 	return imports
 }
 
-// Files parses files and returns the type information
-func Files(files []string, verbose bool, expandEmbedded bool) (map[string]*template.PackageType, error) {
-	typs := make(map[string]*template.PackageType)
-	externals := make(map[string]string)
+// Files parses files and returns the type information. Skipped types are logged at debug level.
+func Files(files []string, logger log.FieldLogger, opts Options) (map[string]*ir.Decl, error) {
+	parsed := make([]*File, 0, len(files))
 	for _, name := range files {
-		fset := token.NewFileSet() // positions are relative to fset
-
-		// Parse the file given in arguments
-		f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		f, err := ParseFile(name, logger, opts)
 		if err != nil {
 			return nil, err
 		}
+		parsed = append(parsed, f)
+	}
+	return Merge(parsed, logger, opts)
+}
 
-		for k, v := range findImports(f) {
-			externals[k] = v
-		}
+// File is what a single Go file declares. Files are parsed on their own, so
+// only the ones that changed have to be parsed again, and combined with Merge.
+type File struct {
+	// Package is the name of the file's package.
+	Package string
 
-		comments := make(map[string]string)
-		for _, v := range f.Comments {
-			c := v.Text()
-			comments[firstWord(c)] = c
-		}
+	// Types are the types declared in the file, by name.
+	Types map[string]*ir.Decl
 
-		bs, err := ioutil.ReadFile(name)
-		if err != nil {
-			return nil, err
-		}
+	// Enums are the constants declared in the file, by the name of their type.
+	Enums map[string][]*ir.EnumValue
 
-	OBJLOOP:
-		for _, v := range f.Scope.Objects {
-			if v.Kind == ast.Typ {
-				comment := comments[v.Name]
-				flags := commentFlags{
-					strict: strings.Contains(comment, "@strict"),
-					ignore: strings.Contains(comment, "@ignore"),
-				}
-				if flags.ignore {
-					if verbose {
-						log.WithField("type_name", v.Name).WithField("file_name", name).Info("skipping type with '@ignore' flag")
-					}
-					continue
-				}
-				ts, ok := v.Decl.(*ast.TypeSpec)
-				if !ok {
-					continue OBJLOOP
-				}
-				t, err := Type(bs, ts, verbose, flags)
-				if err != nil {
-					if verbose {
-						log.WithError(err).WithField("type_name", v.Name).WithField("file_name", name).Error("error parsing type, skipped")
-					}
-					continue OBJLOOP
-				}
-				t.Comment = comment
-				typs[v.Name] = t
+	// Imports are the paths of the imported packages, by package name.
+	Imports map[string]string
+}
+
+// ParseFile parses the types declared in a Go file. Types opts does not select
+// are skipped before they are parsed.
+func ParseFile(name string, logger log.FieldLogger, opts Options) (*File, error) {
+	fset := token.NewFileSet() // positions are relative to fset
+
+	// Parse the file given in arguments
+	f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make(map[string]string)
+	for _, v := range f.Comments {
+		c := v.Text()
+		comments[firstWord(c)] = c
+	}
+
+	bs, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := typeDocs(f)
+	file := &File{
+		Package: f.Name.Name,
+		Types:   make(map[string]*ir.Decl),
+		Enums:   enumValues(fset, f, logger, opts.Diagnostics),
+		Imports: findImports(f),
+	}
+
+OBJLOOP:
+	for _, v := range f.Scope.Objects {
+		if v.Kind == ast.Typ {
+			comment := comments[v.Name]
+			flags := commentFlags{
+				strict:  strings.Contains(comment, "@strict"),
+				inexact: strings.Contains(comment, "@inexact"),
+				ignore:  strings.Contains(comment, "@ignore"),
+				marked:  marked(docs[v.Name]),
+				union:   opts.union(f.Name.Name, v.Name, docs[v.Name]),
+
+				unexported: opts.UnexportedFields,
+			}
+			ts, ok := v.Decl.(*ast.TypeSpec)
+			if !ok {
+				continue OBJLOOP
+			}
+			skipped := diag.Diagnostic{Pos: position(fset, ts.Pos()), Severity: diag.Info, Type: v.Name}
+			if _, ok := ts.Type.(*ast.InterfaceType); !ok && flags.union != nil {
+				report(logger, opts.Diagnostics, diag.Diagnostic{Pos: skipped.Pos, Severity: diag.Warning, Type: v.Name, Reason: "union declared for a type that is not an interface, ignored"})
+				flags.union = nil
+			}
+			if flags.ignore {
+				skipped.Reason = "skipped by its @ignore flag"
+				report(logger, opts.Diagnostics, skipped)
+				continue
+			}
+			if !opts.selects(v.Name) {
+				skipped.Reason = "skipped by the type filters"
+				report(logger, opts.Diagnostics, skipped)
+				continue
 			}
+			t, err := Type(fset, bs, ts, logger, flags, opts.Diagnostics)
+			if err != nil {
+				skipped.Severity, skipped.Reason = diag.Warning, err.Error()
+				report(logger, opts.Diagnostics, skipped)
+				continue OBJLOOP
+			}
+			t.Doc = comment
+			t.Package = f.Name.Name
+			file.Types[v.Name] = t
 		}
 	}
+	return file, nil
+}
 
-	if expandEmbedded {
-		expandEmbeddedTypes(typs, externals)
+// Select returns the types of a parsed model opts selects, such as a model
+// read from JSON: the ones the type filters select, and with Roots or
+// MarkedOnly, the ones reached from them. types is left untouched.
+func Select(types map[string]*ir.Decl, logger log.FieldLogger, opts Options) (map[string]*ir.Decl, error) {
+	byName := make(map[string][]*ir.Decl)
+	for name, d := range types {
+		if !opts.selects(name) {
+			report(logger, opts.Diagnostics, diag.Diagnostic{Pos: d.Pos, Severity: diag.Info, Type: name, Reason: "skipped by the type filters"})
+			continue
+		}
+		byName[name] = []*ir.Decl{d}
 	}
-	return typs, nil
+	byName, err := opts.prune(byName, logger)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]*ir.Decl, len(byName))
+	for name, decls := range byName {
+		selected[name] = decls[0]
+	}
+	return selected, nil
 }
 
-// parseEmbedded nests embedded type fields in the structs containing embedded types
-func expandEmbeddedTypes(types map[string]*template.PackageType, pkgs map[string]string) {
-	for _, v := range types {
-		switch v.Type.(type) {
-		case *template.Struct:
-			s := v.Type.(*template.Struct)
-		EMBEDLOOP:
-			for _, v := range s.Embedded {
-				_v := strings.TrimSpace(v)
-				if _, ok := types[_v]; ok {
-					if s2, ok := types[_v].Type.(*template.Struct); ok {
-						s.Fields = append(s.Fields, s2.Fields...)
-					}
-					continue EMBEDLOOP
-				}
-				if strings.Contains(v, ".") {
-					// Type is in another package
-					str := strings.Split(v, ".")
-					pkg := strings.TrimSpace(str[0])
-					name := strings.TrimSpace(str[1])
-
-					files := []string{}
-					err := Directory(pkgs[pkg], false, &files, false)
-					typs, err := Files(files, false, true)
-					if err != nil {
-						log.WithError(err).Error("error parsing files")
-						continue
-					}
-
-					if _, ok := types[name]; ok {
-						// type already exists
-						continue
-					}
-
-					if typ, ok := typs[name]; ok {
-						switch typ.Type.(type) {
-						case *template.Struct:
-							st := typ.Type.(*template.Struct)
-							s.Fields = append(s.Fields, st.Fields...)
-						default:
-							panic("Embedded type is not struct")
-						}
-					} else {
-						log.WithField("type", _v).Warn("could not find embedded type in external package")
-					}
+// Merge combines parsed files, attaching enum values to their types, keeping
+// the types opts selects, resolving collisions among them and expanding
+// embedded structs. The files are left untouched, so
+// they can be merged again.
+func Merge(files []*File, logger log.FieldLogger, opts Options) (map[string]*ir.Decl, error) {
+	byName := make(map[string][]*ir.Decl)
+	externals := make(map[string]string)
 
-				} else {
-					// do nothing, we can't find the type
+	// enums are keyed by the package qualified name of their type.
+	enums := make(map[string][]*ir.EnumValue)
+	for _, f := range files {
+		for k, v := range f.Imports {
+			externals[k] = v
+		}
+		names := make([]string, 0, len(f.Types))
+		for k := range f.Types {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			v := f.Types[k]
+			d := *v
+			if v.Type != nil {
+				t := *v.Type
+				t.Fields = append([]*ir.Field(nil), t.Fields...)
+				d.Type = &t
+			}
+			byName[k] = append(byName[k], &d)
+		}
+		for k, v := range f.Enums {
+			enums[f.Package+"."+k] = append(enums[f.Package+"."+k], v...)
+		}
+	}
+
+	for name, decls := range byName {
+		for _, t := range decls {
+			values, ok := enums[t.Package+"."+name]
+			if ok && t.Type.Kind == ir.Basic && len(t.Type.Args) == 0 {
+				t.Type = &ir.Type{
+					Kind:   ir.Enum,
+					Name:   t.Type.Name,
+					Values: values,
 				}
 			}
-			s.Embedded = nil
-		default:
+		}
+	}
 
+	// Types that are not drawn cannot collide.
+	byName, err := opts.prune(byName, logger)
+	if err != nil {
+		return nil, err
+	}
+	typs, err := resolveCollisions(byName, opts.Collisions)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ExpandEmbedded {
+		if err := expandEmbeddedTypes(typs, externals, opts, logger); err != nil {
+			return nil, err
 		}
 	}
+	return typs, nil
 }
 
-// Type creates a package level type.
-func Type(bs []byte, ts *ast.TypeSpec, verbose bool, flags commentFlags) (*template.PackageType, error) {
-	s := &template.PackageType{}
+// Type creates a package level type. Skipped fields are reported to diags.
+func Type(fset *token.FileSet, bs []byte, ts *ast.TypeSpec, logger log.FieldLogger, flags commentFlags, diags *diag.List) (*ir.Decl, error) {
+	s := &ir.Decl{}
 	s.Name = ts.Name.Name
+	s.Pos = position(fset, ts.Pos())
+	s.Strict = flags.strict
+	s.Inexact = flags.inexact
+	s.Marked = flags.marked
 	if ts.Comment != nil {
-		s.Comment = ts.Comment.Text()
+		s.Doc = ts.Comment.Text()
+	}
+	if ts.TypeParams != nil {
+		for _, v := range ts.TypeParams.List {
+			for _, n := range v.Names {
+				s.TypeParams = append(s.TypeParams, &ir.TypeParam{
+					Name:       n.Name,
+					Constraint: source(fset, bs, v.Type),
+				})
+			}
+		}
 	}
 
 	switch x := ts.Type.(type) {
@@ -205,15 +326,24 @@ func Type(bs []byte, ts *ast.TypeSpec, verbose bool, flags commentFlags) (*templ
 		return nil, errSkipType
 
 	case *ast.InterfaceType:
-		return nil, errSkipType
+		if flags.union == nil {
+			return nil, errInterface
+		}
+		t, err := flags.union.typ()
+		if err != nil {
+			return nil, err
+		}
+		s.Type = t
+		return s, nil
 
 	case *ast.ArrayType:
 		t, err := parseType(x.Elt)
 		if err != nil {
 			return nil, err
 		}
-		s.Type = &template.Array{
-			Type: t,
+		s.Type = &ir.Type{
+			Kind: ir.Array,
+			Elem: t,
 		}
 		return s, nil
 
@@ -226,115 +356,124 @@ func Type(bs []byte, ts *ast.TypeSpec, verbose bool, flags commentFlags) (*templ
 		if err != nil {
 			return nil, err
 		}
-		s.Type = &template.Map{
-			Key:   key,
-			Value: val,
+		s.Type = &ir.Type{
+			Kind: ir.Map,
+			Key:  key,
+			Elem: val,
 		}
 		return s, nil
 
 	case *ast.StructType:
-		str := &template.Struct{}
-		str.Strict = flags.strict
+		str := &ir.Type{Kind: ir.Struct}
 	FIELDLOOP:
 		for _, v := range x.Fields.List {
 			typ, err := parseType(v.Type)
 			if err != nil {
-				log.WithError(err).Error("error parsing types")
+				skipped := diag.Diagnostic{Pos: position(fset, v.Pos()), Severity: diag.Warning, Type: s.Name, Reason: "field skipped: " + err.Error()}
+				if len(v.Names) > 0 {
+					skipped.Field = v.Names[0].Name
+				}
+				report(logger, diags, skipped)
 				continue FIELDLOOP
 			}
 
-			fld := template.Field{}
+			fld := &ir.Field{}
 			fld.Type = typ
+			fld.Pos = position(fset, v.Pos())
+			if v.Tag != nil {
+				tag, err := strconv.Unquote(v.Tag.Value)
+				if err != nil {
+					tag = v.Tag.Value
+				}
+				fld.Tag = tag
+			}
+			var names []string
 			if v.Names == nil {
 				// No names on a field means it is embedded
-				jsonName := ""
-				if v.Tag != nil {
-					jsonName = strings.Split(template.GetTag("json", v.Tag.Value), ",")[0]
-				}
+				jsonName := strings.Split(template.GetTag("json", fld.Tag), ",")[0]
 				if jsonName == "" {
-					str.Embedded = append(str.Embedded, strings.TrimSpace(string(bs[v.Type.Pos()-2:v.Type.End()-1])))
+					str.Embedded = append(str.Embedded, source(fset, bs, v.Type))
+					str.EmbeddedPos = append(str.EmbeddedPos, fld.Pos)
 					continue FIELDLOOP
 				} else {
 					// A hack to try and process an embedded field as a normal one
-					fld.Name = jsonName
+					names = append(names, jsonName)
 				}
-			} else if v.Names[0] == nil {
-				continue FIELDLOOP
-			} else {
-				fld.Name = v.Names[0].Name
+			}
+			// Fields declared together, as in "A, B string", are one field each.
+			for _, n := range v.Names {
+				if !flags.unexported && !ast.IsExported(n.Name) {
+					// encoding/json skips unexported fields
+					report(logger, diags, diag.Diagnostic{Pos: position(fset, n.Pos()), Severity: diag.Info, Type: s.Name, Field: n.Name, Reason: "unexported field"})
+					continue
+				}
+				names = append(names, n.Name)
 			}
 
 			if v.Doc != nil {
-				fld.DocComment = strings.TrimSuffix(v.Doc.Text(), "\n")
+				fld.Doc = strings.TrimSuffix(v.Doc.Text(), "\n")
 			}
 			if v.Comment != nil {
-				fld.LineComment = strings.TrimSuffix(v.Comment.Text(), "\n")
+				fld.Comment = strings.TrimSuffix(v.Comment.Text(), "\n")
 			}
 
-			if v.Tag != nil && strings.Contains(v.Tag.Value, "json:\"-\"") {
+			if strings.Contains(fld.Tag, "json:\"-\"") {
 				// skip ignored json fields
+				for _, n := range names {
+					report(logger, diags, diag.Diagnostic{Pos: fld.Pos, Severity: diag.Info, Type: s.Name, Field: n, Reason: "skipped by its json tag"})
+				}
 				continue FIELDLOOP
 			}
 
-			// If no tag, still export -- it will still get parsed as json.
-			// so use the name of the field.
-			if v.Tag == nil {
-				fld.Tag = v.Names[0].Name
-			} else {
-				fld.Tag = v.Tag.Value
+			// If no tag, still export -- it will still get parsed as json,
+			// using the name of the field.
+			for _, n := range names {
+				f := *fld
+				f.Name = n
+				str.Fields = append(str.Fields, &f)
 			}
-
-			str.Fields = append(str.Fields, fld)
 		}
 		s.Type = str
 		return s, nil
 
 	default:
-		t := inspectNode(x)
-		s.Type = &t
+		t, err := parseType(x)
+		if err != nil {
+			return nil, err
+		}
+		s.Type = t
 		return s, nil
 
 	}
 }
 
 // parseType parses a non-package level type.
-func parseType(exp ast.Expr) (template.TypeSpec, error) {
-	switch exp.(type) {
+func parseType(exp ast.Expr) (*ir.Type, error) {
+	switch x := exp.(type) {
 	case *ast.ChanType, *ast.FuncLit, *ast.FuncType:
 		// Not supporting goofy things.
 		return nil, errSkipType
 
 	case *ast.InterfaceType:
-		x, ok := exp.(*ast.InterfaceType)
-		if !ok {
-			return nil, errTypeAssert
-		}
 		// Empty interface should be the closes to "any" that we can
 		// get in any language
 		if x.Methods != nil && x.Methods.NumFields() == 0 {
-			return &template.Basic{
-				Type:    template.EmptyInterface,
+			return &ir.Type{
+				Kind:    ir.Basic,
+				Name:    ir.EmptyInterface,
 				Pointer: true,
 			}, nil
 		}
-		return nil, errSkipType
+		return nil, errInlineInterface
 
 	case *ast.ArrayType:
-		x, ok := exp.(*ast.ArrayType)
-		if !ok {
-			return nil, errTypeAssert
-		}
 		t, err := parseType(x.Elt)
 		if err != nil {
 			return nil, err
 		}
-		return &template.Array{Type: t}, nil
+		return &ir.Type{Kind: ir.Array, Elem: t}, nil
 
 	case *ast.MapType:
-		x, ok := exp.(*ast.MapType)
-		if !ok {
-			return nil, errTypeAssert
-		}
 		key, err := parseType(x.Key)
 		if err != nil {
 			return nil, err
@@ -343,51 +482,79 @@ func parseType(exp ast.Expr) (template.TypeSpec, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &template.Map{
-			Key:   key,
-			Value: val,
+		return &ir.Type{
+			Kind: ir.Map,
+			Key:  key,
+			Elem: val,
 		}, nil
 
 	case *ast.StructType:
 		// TODO: Check for structs that need to be parsed.
 		// Example: time.Time should be "Date" in Flow.
-		return &template.Basic{
-			Type:    template.NestedStruct,
+		return &ir.Type{
+			Kind:    ir.Basic,
+			Name:    ir.NestedStruct,
 			Pointer: false,
 		}, nil
 
 	case *ast.BasicLit:
-		x, ok := exp.(*ast.BasicLit)
-		if !ok {
-			return nil, errTypeAssert
-		}
-		return &template.Basic{
-			Type:    x.Kind.String(),
+		return &ir.Type{
+			Kind:    ir.Basic,
+			Name:    x.Kind.String(),
 			Pointer: false,
 		}, nil
 
+	case *ast.StarExpr:
+		t, err := parseType(x.X)
+		if err != nil {
+			return nil, err
+		}
+		t.Pointer = true
+		return t, nil
+
+	case *ast.IndexExpr:
+		// An instantiated generic type: Type[Arg]
+		return instance(x.X, x.Index)
+
+	case *ast.IndexListExpr:
+		// An instantiated generic type: Type[Arg1, Arg2]
+		return instance(x.X, x.Indices...)
+
 	default:
 		t := inspectNode(exp)
 		return &t, nil
 	}
 }
 
+// instance parses an instantiated generic type.
+func instance(generic ast.Expr, args ...ast.Expr) (*ir.Type, error) {
+	t, err := parseType(generic)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range args {
+		arg, err := parseType(v)
+		if err != nil {
+			return nil, err
+		}
+		t.Args = append(t.Args, arg)
+	}
+	return t, nil
+}
+
 // inspectNode checks what is determined to be the value of a node based on a type-assertion.
-func inspectNode(node ast.Node) template.Basic {
-	var t template.Basic
+func inspectNode(node ast.Node) ir.Type {
+	t := ir.Type{Kind: ir.Basic}
 	ast.Inspect(node, func(n ast.Node) bool {
 		switch y := n.(type) {
 		case *ast.BasicLit:
-			t.Type = y.Value
+			t.Name = y.Value
 		case *ast.Ident:
-			if t.Type == "" {
-				t.Type = y.Name
+			if t.Name == "" {
+				t.Name = y.Name
 			} else {
 				// Selector expr: package.Type
-				t.Type += "." + y.Name
-			}
-			if y.Obj != nil {
-
+				t.Name += "." + y.Name
 			}
 		case *ast.StarExpr:
 			t.Pointer = true
@@ -397,6 +564,53 @@ func inspectNode(node ast.Node) template.Basic {
 	return t
 }
 
+// position returns the position of a node in a file.
+func position(fset *token.FileSet, pos token.Pos) ir.Position {
+	p := fset.Position(pos)
+	return ir.Position{File: p.Filename, Line: p.Line, Column: p.Column}
+}
+
+// source returns the source code of a node.
+func source(fset *token.FileSet, bs []byte, n ast.Node) string {
+	return strings.TrimSpace(string(bs[fset.Position(n.Pos()).Offset:fset.Position(n.End()).Offset]))
+}
+
+// typeDocs returns the raw doc comment lines of every type, by name: its own
+// doc comment, or that of its declaration when it declares a single type.
+// Directives are dropped from comment text, so they are read from here.
+func typeDocs(f *ast.File) map[string][]string {
+	docs := make(map[string][]string)
+	for _, d := range f.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			for _, doc := range []*ast.CommentGroup{gd.Doc, ts.Doc} {
+				if doc == nil || (doc == gd.Doc && len(gd.Specs) > 1) {
+					continue
+				}
+				for _, c := range doc.List {
+					docs[ts.Name.Name] = append(docs[ts.Name.Name], c.Text)
+				}
+			}
+		}
+	}
+	return docs
+}
+
+// marked reports whether a raw doc comment holds a marker: an @typewriter
+// flag or a //tw:generate directive.
+func marked(doc []string) bool {
+	for _, c := range doc {
+		if strings.Contains(c, "@typewriter") || strings.HasPrefix(c, "//tw:generate") {
+			return true
+		}
+	}
+	return false
+}
+
 // first word returns the first word of a string
 func firstWord(value string) string {
 	for i := range value {
@@ -0,0 +1,720 @@
+package parse
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/natdm/typewriter/diag"
+	"github.com/natdm/typewriter/ir"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+type ParseTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestParseTestSuite(t *testing.T) {
+	suite.Run(t, new(ParseTestSuite))
+}
+
+func (s *ParseTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "typewriter")
+	s.Require().NoError(err)
+	s.dir = dir
+}
+
+func (s *ParseTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+// parse parses Go source as a single file.
+func (s *ParseTestSuite) parse(src string) map[string]*ir.Decl {
+	name := filepath.Join(s.dir, "types.go")
+	s.Require().NoError(ioutil.WriteFile(name, []byte(src), 0644))
+	types, err := Files([]string{name}, log.New(), Options{})
+	s.Require().NoError(err)
+	return types
+}
+
+func (s *ParseTestSuite) TestStruct() {
+	types := s.parse(`package models
+
+// User is a user
+type User struct {
+	// Name is a name
+	Name  string ` + "`json:\"name\"`" + ` // line
+	Email *string
+	Skip  int ` + "`json:\"-\"`" + `
+}
+`)
+	s.Require().Contains(types, "User")
+	u := types["User"]
+	s.Equal("models", u.Package)
+	s.Equal("User is a user\n", u.Doc)
+	s.Equal(4, u.Pos.Line)
+	s.Require().Len(u.Type.Fields, 2)
+
+	name := u.Type.Fields[0]
+	s.Equal("Name", name.Name)
+	s.Equal(`json:"name"`, name.Tag)
+	s.Equal("Name is a name", name.Doc)
+	s.Equal("line", name.Comment)
+	s.Equal(6, name.Pos.Line)
+
+	email := u.Type.Fields[1]
+	s.Equal("", email.Tag)
+	s.Equal(&ir.Type{Kind: ir.Basic, Name: "string", Pointer: true}, email.Type)
+}
+
+func (s *ParseTestSuite) TestEnums() {
+	types := s.parse(`package models
+
+type Color string
+
+const (
+	Red   Color = "red"
+	Green Color = "green"
+)
+
+type Level int
+
+const (
+	Low Level = iota + 1
+	_
+	High
+	Other = 5
+)
+`)
+	color := types["Color"].Type
+	s.Equal(ir.Enum, color.Kind)
+	s.Equal("string", color.Name)
+	s.Require().Len(color.Values, 2)
+	s.Equal(`"red"`, color.Values[0].Value)
+	s.Equal("Green", color.Values[1].Name)
+
+	level := types["Level"].Type
+	s.Equal(ir.Enum, level.Kind)
+	s.Require().Len(level.Values, 2)
+	s.Equal("1", level.Values[0].Value)
+	s.Equal("High", level.Values[1].Name)
+	s.Equal("3", level.Values[1].Value)
+}
+
+func (s *ParseTestSuite) TestGenerics() {
+	types := s.parse(`package models
+
+type Page[T any, K comparable] struct {
+	Items []T ` + "`json:\"items\"`" + `
+	Next  *K  ` + "`json:\"next\"`" + `
+}
+
+type Users Page[User, int]
+
+type User struct{}
+`)
+	page := types["Page"]
+	s.Equal([]*ir.TypeParam{{Name: "T", Constraint: "any"}, {Name: "K", Constraint: "comparable"}}, page.TypeParams)
+
+	users := types["Users"].Type
+	s.Equal("Page", users.Name)
+	s.Equal([]*ir.Type{{Kind: ir.Basic, Name: "User"}, {Kind: ir.Basic, Name: "int"}}, users.Args)
+}
+
+func (s *ParseTestSuite) TestMergeLeavesFilesUntouched() {
+	name := filepath.Join(s.dir, "types.go")
+	s.Require().NoError(ioutil.WriteFile(name, []byte(`package models
+
+type Base struct {
+	ID int `+"`json:\"id\"`"+`
+}
+
+type User struct {
+	Base
+	Name string `+"`json:\"name\"`"+`
+}
+
+type Role string
+
+const Admin Role = "admin"
+`), 0644))
+	f, err := ParseFile(name, log.New(), Options{})
+	s.Require().NoError(err)
+
+	for i := 0; i < 2; i++ {
+		types, err := Merge([]*File{f}, log.New(), Options{ExpandEmbedded: true})
+		s.Require().NoError(err)
+		s.Len(types["User"].Type.Fields, 2)
+		s.Equal(ir.Enum, types["Role"].Type.Kind)
+	}
+	s.Len(f.Types["User"].Type.Fields, 1)
+	s.Equal([]string{"Base"}, f.Types["User"].Type.Embedded)
+	s.Equal(ir.Basic, f.Types["Role"].Type.Kind)
+}
+
+func (s *ParseTestSuite) TestCollisions() {
+	write := func(dir, src string) string {
+		s.Require().NoError(os.MkdirAll(filepath.Join(s.dir, dir), 0755))
+		name := filepath.Join(s.dir, dir, "types.go")
+		s.Require().NoError(ioutil.WriteFile(name, []byte(src), 0644))
+		return name
+	}
+	files := []string{
+		write("models", `package models
+
+type Config struct {
+	Events events.Config `+"`json:\"events\"`"+`
+	Self   *Config       `+"`json:\"self\"`"+`
+}
+
+type Level string
+
+const Debug Level = "debug"
+
+//tw:union Config events.Config
+type Configured interface{}
+`),
+		write("events", `package events
+
+type Config struct {
+	Level Level `+"`json:\"level\"`"+`
+}
+
+type Level int
+
+const Loud Level = 2
+`),
+	}
+
+	_, err := Files(files, log.New(), Options{})
+	s.Require().Error(err)
+	collision, ok := err.(*CollisionError)
+	s.Require().True(ok)
+	s.Equal("Config", collision.Name)
+	s.Contains(err.Error(), files[0]+":3:6 (package models)")
+	s.Contains(err.Error(), files[1]+":3:6 (package events)")
+
+	types, err := Files(files, log.New(), Options{Collisions: CollisionsPrefix})
+	s.Require().NoError(err)
+	s.Len(types, 5)
+	models := types["ModelsConfig"].Type
+	s.Equal("ModelsConfig", types["ModelsConfig"].Name)
+	s.Equal("EventsConfig", models.Fields[0].Type.Name)
+	s.Equal("ModelsConfig", models.Fields[1].Type.Name)
+	s.Equal("EventsLevel", types["EventsConfig"].Type.Fields[0].Type.Name)
+	s.Equal([]*ir.EnumValue{{Name: "Debug", Value: `"debug"`, Pos: types["ModelsLevel"].Type.Values[0].Pos}}, types["ModelsLevel"].Type.Values)
+	s.Equal("2", types["EventsLevel"].Type.Values[0].Value)
+	variants := types["Configured"].Type.Variants
+	s.Require().Len(variants, 2)
+	s.Equal("ModelsConfig", variants[0].Type.Name)
+	s.Equal("EventsConfig", variants[1].Type.Name)
+}
+
+func (s *ParseTestSuite) TestCollisionsOfMainPackages() {
+	write := func(dir string) string {
+		s.Require().NoError(os.MkdirAll(filepath.Join(s.dir, dir), 0755))
+		name := filepath.Join(s.dir, dir, "main.go")
+		s.Require().NoError(ioutil.WriteFile(name, []byte(`package main
+
+type Config struct {
+	Self *Config `+"`json:\"self\"`"+`
+}
+`), 0644))
+		return name
+	}
+	files := []string{write("cmd/server"), write("cmd/my-worker")}
+
+	types, err := Files(files, log.New(), Options{Collisions: CollisionsPrefix})
+	s.Require().NoError(err)
+	s.Len(types, 2)
+	s.Equal("ServerConfig", types["ServerConfig"].Type.Fields[0].Type.Name)
+	s.Equal("MyWorkerConfig", types["MyWorkerConfig"].Type.Fields[0].Type.Name)
+}
+
+func (s *ParseTestSuite) TestFilters() {
+	name := filepath.Join(s.dir, "types.go")
+	s.Require().NoError(ioutil.WriteFile(name, []byte(`package models
+
+type UserRequest struct{}
+
+type UserResponse struct{}
+
+type userCache struct{}
+
+type Session struct{}
+`), 0644))
+
+	parse := func(opts Options) []string {
+		types, err := Files([]string{name}, log.New(), opts)
+		s.Require().NoError(err)
+		var names []string
+		for k := range types {
+			names = append(names, k)
+		}
+		return names
+	}
+
+	s.ElementsMatch([]string{"UserRequest", "UserResponse", "Session"}, parse(Options{ExportedOnly: true}))
+	s.ElementsMatch([]string{"UserRequest", "UserResponse"}, parse(Options{IncludeTypes: regexp.MustCompile("^User")}))
+	s.ElementsMatch([]string{"UserResponse", "userCache", "Session"}, parse(Options{ExcludeTypes: regexp.MustCompile("Request$")}))
+}
+
+func (s *ParseTestSuite) TestMarkedOnly() {
+	api := filepath.Join(s.dir, "api.go")
+	s.Require().NoError(ioutil.WriteFile(api, []byte(`package api
+
+import "example.com/models"
+
+// CreateOrder is a payload.
+// @typewriter
+type CreateOrder struct {
+	Items []models.Item
+}
+
+//tw:generate
+type OrderCreated struct {
+	ID string
+}
+
+type (
+	//tw:generate
+	Grouped struct{}
+
+	Internal struct{}
+)
+`), 0644))
+	models := filepath.Join(s.dir, "models.go")
+	s.Require().NoError(ioutil.WriteFile(models, []byte(`package models
+
+type Item struct {
+	Price Price
+}
+
+type Price int
+
+type Cache struct{}
+`), 0644))
+
+	types, err := Files([]string{api, models}, log.New(), Options{})
+	s.Require().NoError(err)
+	s.Len(types, 7)
+	s.True(types["CreateOrder"].Marked)
+	s.False(types["Internal"].Marked)
+
+	types, err = Files([]string{api, models}, log.New(), Options{MarkedOnly: true})
+	s.Require().NoError(err)
+	var names []string
+	for k := range types {
+		names = append(names, k)
+	}
+	s.ElementsMatch([]string{"CreateOrder", "OrderCreated", "Grouped", "Item", "Price"}, names)
+}
+
+func (s *ParseTestSuite) TestMarkedOnlyCollisions() {
+	a := filepath.Join(s.dir, "a.go")
+	s.Require().NoError(ioutil.WriteFile(a, []byte(`package a
+
+//tw:generate
+type Payload struct {
+	Settings Settings
+}
+
+type Settings struct{}
+
+type Config struct{}
+`), 0644))
+	b := filepath.Join(s.dir, "b.go")
+	s.Require().NoError(ioutil.WriteFile(b, []byte(`package b
+
+type Config struct{}
+
+type Settings struct{}
+`), 0644))
+
+	_, err := Files([]string{a, b}, log.New(), Options{})
+	s.IsType(&CollisionError{}, err)
+
+	// Types that are not reached do not collide, and references resolve
+	// within their package.
+	types, err := Files([]string{a, b}, log.New(), Options{MarkedOnly: true})
+	s.Require().NoError(err)
+	s.Len(types, 2)
+	s.Equal("a", types["Settings"].Package)
+
+	_, err = Files([]string{a, b}, log.New(), Options{Roots: []string{"Config"}})
+	s.IsType(&CollisionError{}, err, "colliding roots still collide")
+	types, err = Files([]string{a, b}, log.New(), Options{Roots: []string{"b.Config"}})
+	s.Require().NoError(err)
+	s.Equal("b", types["Config"].Package)
+}
+
+func (s *ParseTestSuite) TestConstraints() {
+	files := map[string]string{
+		"types.go":         "package models\n",
+		"types_windows.go": "package models\n",
+		"types_linux.go":   "package models\n",
+		"ignored.go":       "//go:build ignore\n\npackage main\n",
+		"pro.go":           "//go:build pro && !windows\n\npackage models\n",
+	}
+	for name, src := range files {
+		s.Require().NoError(ioutil.WriteFile(filepath.Join(s.dir, name), []byte(src), 0644))
+	}
+
+	list := func(ctx *build.Context) []string {
+		var out []string
+		s.Require().NoError(DirectoryFunc(s.dir, false, Constraints(ctx), &out))
+		var names []string
+		for _, v := range out {
+			names = append(names, filepath.Base(v))
+		}
+		return names
+	}
+
+	ctx := build.Default
+	ctx.GOOS = "linux"
+	s.ElementsMatch([]string{"types.go", "types_linux.go"}, list(&ctx))
+
+	ctx.BuildTags = []string{"pro"}
+	s.ElementsMatch([]string{"types.go", "types_linux.go", "pro.go"}, list(&ctx))
+
+	ctx.GOOS = "windows"
+	s.ElementsMatch([]string{"types.go", "types_windows.go"}, list(&ctx))
+}
+
+func (s *ParseTestSuite) TestUnexportedFields() {
+	name := filepath.Join(s.dir, "types.go")
+	s.Require().NoError(ioutil.WriteFile(name, []byte(`package models
+
+type User struct {
+	Name      string
+	createdAt string `+"`json:\"created_at\"`"+`
+	_         int
+}
+`), 0644))
+
+	fields := func(opts Options) []string {
+		types, err := Files([]string{name}, log.New(), opts)
+		s.Require().NoError(err)
+		var names []string
+		for _, f := range types["User"].Type.Fields {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+
+	s.Equal([]string{"Name"}, fields(Options{}))
+	s.Equal([]string{"Name", "createdAt", "_"}, fields(Options{UnexportedFields: true}))
+}
+
+func (s *ParseTestSuite) TestEmbeddedPromotion() {
+	name := filepath.Join(s.dir, "types.go")
+	s.Require().NoError(ioutil.WriteFile(name, []byte(`package models
+
+type Response struct {
+	ID string
+	*Meta
+	Left
+	Right
+}
+
+type Meta struct {
+	ID      string
+	Version int
+	Audit
+}
+
+type Audit struct {
+	Version int
+	By      string
+}
+
+type Left struct {
+	Name  string
+	Color string `+"`json:\"Color,omitempty\"`"+`
+	Shared
+}
+
+type Right struct {
+	Name  string
+	Color string
+	Shared
+}
+
+type Shared struct {
+	Owner string
+}
+
+type Point struct {
+	X, Y int
+}
+`), 0644))
+
+	fields := func(d *ir.Decl) []string {
+		var names []string
+		for _, f := range d.Type.Fields {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+
+	types, err := Files([]string{name}, log.New(), Options{ExpandEmbedded: true})
+	s.Require().NoError(err)
+
+	// ID is hidden by Response's own, Meta's Version hides Audit's, Name
+	// collides in Left and Right, Left's tagged Color wins over Right's, and
+	// Shared is embedded twice at the same depth.
+	s.Equal([]string{"ID", "Version", "By", "Color"}, fields(types["Response"]))
+	s.Equal(`json:"Color,omitempty"`, types["Response"].Type.Fields[3].Tag)
+	s.Empty(types["Response"].Type.Embedded)
+	s.Equal([]string{"Name", "Color", "Owner"}, fields(types["Left"]))
+	s.Equal([]string{"X", "Y"}, fields(types["Point"]))
+}
+
+func (s *ParseTestSuite) TestEmbedCycle() {
+	name := filepath.Join(s.dir, "types.go")
+	s.Require().NoError(ioutil.WriteFile(name, []byte(`package models
+
+type Node struct {
+	*Tree
+}
+
+type Tree struct {
+	Branch
+}
+
+type Branch struct {
+	*Tree
+}
+`), 0644))
+
+	_, err := Files([]string{name}, log.New(), Options{ExpandEmbedded: true})
+	s.Require().IsType(&EmbedCycleError{}, err)
+	s.Equal(name+":11:6: Branch embeds itself: Branch -> *Tree -> Branch", err.Error())
+
+	_, err = Files([]string{name}, log.New(), Options{})
+	s.NoError(err, "cycles only matter when expanding")
+}
+
+func (s *ParseTestSuite) TestEmbeddedNonStruct() {
+	gopath := os.Getenv("GOPATH")
+	defer os.Setenv("GOPATH", gopath)
+	os.Setenv("GOPATH", s.dir)
+
+	pkg := filepath.Join(s.dir, "src", "example.com", "tags")
+	s.Require().NoError(os.MkdirAll(pkg, 0755))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(pkg, "tags.go"), []byte(`package tags
+
+type Tags []string
+
+type Base struct {
+	ID string
+	Kind
+}
+
+type Kind string
+`), 0644))
+
+	name := filepath.Join(s.dir, "types.go")
+	s.Require().NoError(ioutil.WriteFile(name, []byte(`package models
+
+import "example.com/tags"
+
+type Labels map[string]string
+
+type color string
+
+type Item struct {
+	Name string
+	Labels
+	*tags.Tags
+	tags.Base
+	color
+}
+`), 0644))
+
+	types, err := Files([]string{name}, log.New(), Options{ExpandEmbedded: true})
+	s.Require().NoError(err)
+	fields := types["Item"].Type.Fields
+	s.Require().Len(fields, 5)
+	s.Equal("Name", fields[0].Name)
+	s.Equal(&ir.Field{Name: "Labels", Type: &ir.Type{Kind: ir.Basic, Name: "Labels"}}, fields[1])
+	s.Equal(&ir.Field{Name: "Tags", Type: &ir.Type{Kind: ir.Basic, Name: "tags.Tags", Pointer: true}}, fields[2])
+	s.Equal("ID", fields[3].Name)
+	s.Equal(&ir.Field{Name: "Kind", Type: &ir.Type{Kind: ir.Basic, Name: "tags.Kind"}}, fields[4])
+}
+
+func (s *ParseTestSuite) TestEmbeddedStandardLibrary() {
+	gopath := os.Getenv("GOPATH")
+	defer os.Setenv("GOPATH", gopath)
+	os.Setenv("GOPATH", s.dir)
+
+	name := filepath.Join(s.dir, "types.go")
+	s.Require().NoError(ioutil.WriteFile(name, []byte(`package models
+
+import (
+	"net/url"
+	"time"
+)
+
+type Query struct {
+	time.Duration
+	url.Values
+}
+`), 0644))
+
+	var diags diag.List
+	types, err := Files([]string{name}, log.New(), Options{ExpandEmbedded: true, Diagnostics: &diags})
+	s.Require().NoError(err)
+	s.Empty(diags.Diagnostics(diag.Info))
+	s.Equal([]*ir.Field{
+		{Name: "Duration", Type: &ir.Type{Kind: ir.Basic, Name: "time.Duration"}},
+		{Name: "Values", Type: &ir.Type{Kind: ir.Basic, Name: "url.Values"}},
+	}, types["Query"].Type.Fields)
+}
+
+func (s *ParseTestSuite) TestEmbeddedDiagnostics() {
+	name := filepath.Join(s.dir, "types.go")
+	s.Require().NoError(ioutil.WriteFile(name, []byte(`package models
+
+type color string
+
+type Item struct {
+	Name string
+	color
+	*Missing
+}
+`), 0644))
+
+	var diags diag.List
+	_, err := Files([]string{name}, log.New(), Options{ExpandEmbedded: true, Diagnostics: &diags})
+	s.Require().NoError(err)
+
+	var lines []string
+	for _, d := range diags.Diagnostics(diag.Info) {
+		lines = append(lines, strings.TrimPrefix(d.String(), name))
+	}
+	s.Equal([]string{
+		":7:2: info: Item: embedded type color is unexported and not a struct, skipped",
+		":8:2: warning: Item: embedded type Missing could not be found, skipped",
+	}, lines, "problems are reported where the type is embedded")
+}
+
+func (s *ParseTestSuite) TestDiagnostics() {
+	name := filepath.Join(s.dir, "types.go")
+	s.Require().NoError(ioutil.WriteFile(name, []byte(`package models
+
+// Skipped is skipped.
+// @ignore
+type Skipped struct{}
+
+type Hub interface{}
+
+type User struct {
+	Name     string
+	password string
+	Secret   string `+"`json:\"-\"`"+`
+	Notify   chan string
+	Shape    interface{ Area() float64 }
+}
+`), 0644))
+
+	var diags diag.List
+	_, err := Files([]string{name}, log.New(), Options{Diagnostics: &diags})
+	s.Require().NoError(err)
+
+	var lines []string
+	for _, d := range diags.Diagnostics(diag.Info) {
+		lines = append(lines, strings.TrimPrefix(d.String(), name))
+	}
+	s.Equal([]string{
+		":5:6: info: Skipped: skipped by its @ignore flag",
+		":7:6: warning: Hub: interface without declared implementations",
+		":11:2: info: User.password: unexported field",
+		":12:2: info: User.Secret: skipped by its json tag",
+		":13:2: warning: User.Notify: field skipped: not a supported type",
+		":14:2: warning: User.Shape: field skipped: interface with methods, declare it as a named type with a //tw:union directive to draw it",
+	}, lines)
+}
+
+func (s *ParseTestSuite) TestUnions() {
+	name := filepath.Join(s.dir, "types.go")
+	s.Require().NoError(ioutil.WriteFile(name, []byte(`package shapes
+
+// Shape is a shape.
+//tw:union Circle Square=square
+//tw:discriminator kind
+type Shape interface {
+	Area() float64
+}
+
+//tw:union Circle Square
+type Drawable interface {
+	Draw()
+}
+
+type Painter interface {
+	Paint()
+}
+
+type Circle struct {
+	Radius float64
+}
+
+type Square struct {
+	Side float64
+}
+
+type Canvas struct {
+	Shapes []Shape
+}
+`), 0644))
+
+	var diags diag.List
+	types, err := Files([]string{name}, log.New(), Options{Diagnostics: &diags})
+	s.Require().NoError(err)
+	s.Equal("Shape is a shape.\n", types["Shape"].Doc)
+	s.Equal(&ir.Type{Kind: ir.Union, Discriminator: "kind", Variants: []*ir.Variant{
+		{Type: &ir.Type{Kind: ir.Basic, Name: "Circle"}, Tag: "Circle"},
+		{Type: &ir.Type{Kind: ir.Basic, Name: "Square"}, Tag: "square"},
+	}}, types["Shape"].Type)
+	s.Equal(ir.Union, types["Drawable"].Type.Kind)
+	s.Empty(types["Drawable"].Type.Discriminator)
+	s.NotContains(types, "Painter")
+
+	// Unions by name replace the directives.
+	types, err = Files([]string{name}, log.New(), Options{Unions: map[string]Union{
+		"shapes.Painter": {Variants: []string{"Circle"}},
+		"Drawable":       {Discriminator: "type", Variants: []string{"other.Circle", "Square"}},
+	}})
+	s.Require().NoError(err)
+	s.Equal(&ir.Type{Kind: ir.Union, Discriminator: "type", Variants: []*ir.Variant{
+		{Type: &ir.Type{Kind: ir.Basic, Name: "other.Circle"}, Tag: "Circle"},
+		{Type: &ir.Type{Kind: ir.Basic, Name: "Square"}, Tag: "Square"},
+	}}, types["Drawable"].Type)
+	s.Equal(ir.Union, types["Painter"].Type.Kind)
+
+	// Unions are roots of what they refer to.
+	types, err = Files([]string{name}, log.New(), Options{Roots: []string{"Shape"}})
+	s.Require().NoError(err)
+	s.Len(types, 3)
+
+	// Variants sharing a tag are an error.
+	_, err = Files([]string{name}, log.New(), Options{Diagnostics: &diags, Unions: map[string]Union{
+		"Shape":  {Discriminator: "kind", Variants: []string{"Circle=round", "Square=round"}},
+		"Canvas": {Variants: []string{"Circle"}},
+	}})
+	s.Require().NoError(err)
+	var reasons []string
+	for _, d := range diags.Diagnostics(diag.Warning) {
+		reasons = append(reasons, d.Type+": "+d.Reason)
+	}
+	s.Contains(reasons, `Shape: union variants Circle and Square share the tag "round"`)
+	s.Contains(reasons, "Canvas: union declared for a type that is not an interface, ignored")
+	s.Contains(reasons, "Painter: interface without declared implementations")
+}
@@ -0,0 +1,106 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/natdm/typewriter/ir"
+)
+
+// This file contains the interfaces drawn as unions of their declared implementations.
+
+var (
+	errInterface       = errors.New("interface without declared implementations")
+	errInlineInterface = errors.New("interface with methods, declare it as a named type with a //tw:union directive to draw it")
+)
+
+// Union declares the types implementing an interface, so the interface is
+// drawn as one of them instead of being skipped.
+type Union struct {
+	// Discriminator is the JSON field the variants are told apart by, such as
+	// "kind". Without it, variants are told apart by their shape.
+	Discriminator string `yaml:"discriminator" json:"discriminator"`
+
+	// Variants are the implementing types, such as "Circle", tagged with their
+	// name in the discriminator field, or "Circle=circle" to tag it "circle".
+	Variants []string `yaml:"variants" json:"variants"`
+}
+
+// unionDirectives returns the union declared by the //tw:union and
+// //tw:discriminator directives of a doc comment, if any:
+//
+//	//tw:union Circle Square=square
+//	//tw:discriminator kind
+func unionDirectives(doc []string) *Union {
+	var u *Union
+	for _, c := range doc {
+		if v, ok := directive(c, "union"); ok {
+			if u == nil {
+				u = &Union{}
+			}
+			u.Variants = append(u.Variants, strings.Fields(v)...)
+		}
+	}
+	for _, c := range doc {
+		if v, ok := directive(c, "discriminator"); ok && u != nil {
+			u.Discriminator = strings.TrimSpace(v)
+		}
+	}
+	return u
+}
+
+// directive returns the arguments of a //tw:<name> directive comment.
+func directive(comment, name string) (string, bool) {
+	prefix := "//tw:" + name
+	if !strings.HasPrefix(comment, prefix) {
+		return "", false
+	}
+	args := comment[len(prefix):]
+	if args != "" && args[0] != ' ' && args[0] != '\t' {
+		return "", false
+	}
+	return args, true
+}
+
+// typ returns the Union type of the union's variants. Unions without variants,
+// and variants sharing a tag, are an error.
+func (u *Union) typ() (*ir.Type, error) {
+	if len(u.Variants) == 0 {
+		return nil, errors.New("union without variants")
+	}
+	t := &ir.Type{Kind: ir.Union, Discriminator: u.Discriminator}
+	tags := make(map[string]string)
+	for _, v := range u.Variants {
+		name, tag := v, v
+		if i := strings.Index(v, "="); i >= 0 {
+			name, tag = v[:i], v[i+1:]
+		} else if i := strings.LastIndex(v, "."); i >= 0 {
+			// Variants from other packages are tagged with their bare name.
+			tag = v[i+1:]
+		}
+		name = strings.TrimPrefix(name, "*")
+		if name == "" || tag == "" {
+			return nil, fmt.Errorf("union variant %q without a type or tag", v)
+		}
+		if u.Discriminator != "" {
+			if other, ok := tags[tag]; ok {
+				return nil, fmt.Errorf("union variants %s and %s share the tag %q", other, name, tag)
+			}
+			tags[tag] = name
+		}
+		t.Variants = append(t.Variants, &ir.Variant{Type: &ir.Type{Kind: ir.Basic, Name: name}, Tag: tag})
+	}
+	return t, nil
+}
+
+// union returns the union declared for the type name of package pkg, by
+// Options.Unions or else by its directives.
+func (o Options) union(pkg, name string, doc []string) *Union {
+	for _, key := range []string{pkg + "." + name, name} {
+		if u, ok := o.Unions[key]; ok {
+			return &u
+		}
+	}
+	return unionDirectives(doc)
+}
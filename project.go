@@ -0,0 +1,256 @@
+package typewriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/natdm/typewriter/parse"
+	"github.com/natdm/typewriter/template"
+	"gopkg.in/yaml.v2"
+)
+
+// This file contains the project file, which describes a whole run instead of flags.
+
+// ProjectFiles are the names FindProject looks for, in order.
+var ProjectFiles = []string{"typewriter.yaml", "typewriter.yml", ".typewriter.json"}
+
+// Project is a project file, such as typewriter.yaml:
+//
+//	dir: ./models
+//	exclude: ["*_gen.go"]
+//	expandEmbedded: true
+//	types:
+//	  uuid.UUID: string
+//	naming: camel
+//	ts:
+//	  export: true
+//	targets:
+//	  - lang: flow
+//	    out: ./web/models.js
+//	  - lang: ts
+//	    out: ./web/models.ts
+//	    types:
+//	      time.Time: string
+//
+// Paths are relative to the project file.
+type Project struct {
+	// Dir is the directory to parse types from. Defaults to the project file's directory.
+	Dir string `yaml:"dir" json:"dir"`
+
+	// Files are the Go files to parse, instead of Dir.
+	Files []string `yaml:"files" json:"files"`
+
+	// Recursive parses every directory below Dir too. Defaults to true, like the -r flag.
+	Recursive bool `yaml:"recursive" json:"recursive"`
+
+	// Include are globs of the Go files to parse. See Config.Include.
+	Include []string `yaml:"include" json:"include"`
+
+	// Exclude are globs of Go files not to parse. See Config.Exclude.
+	Exclude []string `yaml:"exclude" json:"exclude"`
+
+	// IncludeTypes is a regular expression of the type names to parse.
+	IncludeTypes string `yaml:"includeTypes" json:"includeTypes"`
+
+	// ExcludeTypes is a regular expression of the type names to skip.
+	ExcludeTypes string `yaml:"excludeTypes" json:"excludeTypes"`
+
+	// ExportedOnly skips unexported types.
+	ExportedOnly bool `yaml:"exportedOnly" json:"exportedOnly"`
+
+	// UnexportedFields draws unexported struct fields too. See Config.UnexportedFields.
+	UnexportedFields bool `yaml:"unexportedFields" json:"unexportedFields"`
+
+	// MarkedOnly parses only marked types, and the types they refer to. See Config.MarkedOnly.
+	MarkedOnly bool `yaml:"markedOnly" json:"markedOnly"`
+
+	// Roots are the types to draw, with the types they refer to. See Config.Roots.
+	Roots []string `yaml:"roots" json:"roots"`
+
+	// Unions declare the implementations of interfaces. See Config.Unions.
+	Unions map[string]parse.Union `yaml:"unions" json:"unions"`
+
+	// Tags are the build tags files are selected with, on top of GOOS and GOARCH.
+	Tags []string `yaml:"tags" json:"tags"`
+
+	// GOOS and GOARCH select files by build constraints and name suffixes
+	// for another platform than the host's.
+	GOOS   string `yaml:"goos" json:"goos"`
+	GOARCH string `yaml:"goarch" json:"goarch"`
+
+	ExpandEmbedded bool `yaml:"expandEmbedded" json:"expandEmbedded"`
+
+	// Collisions is how types of the same name in different packages are
+	// handled: "error", the default, or "prefix".
+	Collisions string `yaml:"collisions" json:"collisions"`
+
+	// Templates are directories of template packs to load, see
+	// template.LoadTemplates, named after the directory or, written as
+	// "name=dir", by name.
+	Templates []string `yaml:"templates" json:"templates"`
+
+	// Types map Go types to the type drawn in their place, for every target.
+	Types map[string]string `yaml:"types" json:"types"`
+
+	// Naming names the fields without a name in their json tag: "camel" or "snake".
+	Naming template.Naming `yaml:"naming" json:"naming"`
+
+	// EnumUnions draws types with declared constants as a union of their values.
+	EnumUnions bool `yaml:"enumUnions" json:"enumUnions"`
+
+	TS   template.TSOptions   `yaml:"ts" json:"ts"`
+	Flow template.FlowOptions `yaml:"flow" json:"flow"`
+
+	Targets []ProjectTarget `yaml:"targets" json:"targets"`
+}
+
+// ProjectTarget is a language to draw and the file to draw it to.
+type ProjectTarget struct {
+	// Lang is the name of a registered language.
+	Lang string `yaml:"lang" json:"lang"`
+
+	// Out is the file to write. Empty writes to standard output.
+	// With Split, it is the directory to write the files to.
+	Out string `yaml:"out" json:"out"`
+
+	// Split draws a file per Go "package" or per "type".
+	Split string `yaml:"split" json:"split"`
+
+	// Index draws an index file re-exporting every split file.
+	Index bool `yaml:"index" json:"index"`
+
+	// Types map Go types for this target only, over the project's Types.
+	Types map[string]string `yaml:"types" json:"types"`
+}
+
+// FindProject returns the path of the first of ProjectFiles in dir, or ""
+// when there is none.
+func FindProject(dir string) (string, error) {
+	for _, name := range ProjectFiles {
+		path := filepath.Join(dir, name)
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// LoadProject reads a project file. Files ending in .json are read as JSON,
+// anything else as YAML. Unknown keys are an error, to catch typos.
+func LoadProject(path string) (*Project, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Project{Recursive: true}
+	if filepath.Ext(path) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(bs))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(p)
+	} else {
+		err = yaml.UnmarshalStrict(bs, p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if !p.Naming.Valid() {
+		return nil, fmt.Errorf("%s: unknown naming strategy %q", path, p.Naming)
+	}
+	if _, err := parse.ParseCollisions(p.Collisions); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	base := filepath.Dir(path)
+	if p.Dir == "" {
+		p.Dir = "."
+	}
+	p.Dir = resolve(base, p.Dir)
+	for i := range p.Files {
+		p.Files[i] = resolve(base, p.Files[i])
+	}
+	for i, v := range p.Templates {
+		name, dir := templatePack(v)
+		if name != "" {
+			name += "="
+		}
+		p.Templates[i] = name + resolve(base, dir)
+	}
+	for i := range p.Targets {
+		p.Targets[i].Out = resolve(base, p.Targets[i].Out)
+	}
+	return p, nil
+}
+
+// Options returns the drawing options of a target.
+func (p *Project) Options(t ProjectTarget) template.Options {
+	opts := template.Options{TS: p.TS, Flow: p.Flow, Naming: p.Naming, EnumUnions: p.EnumUnions}
+	if len(p.Types)+len(t.Types) > 0 {
+		opts.Types = make(map[string]string)
+		for k, v := range p.Types {
+			opts.Types[k] = v
+		}
+		for k, v := range t.Types {
+			opts.Types[k] = v
+		}
+	}
+	return opts
+}
+
+// LoadTemplates loads and registers every template pack of Templates, in order.
+func (p *Project) LoadTemplates() ([]template.Language, error) {
+	var langs []template.Language
+	for _, v := range p.Templates {
+		name, dir := templatePack(v)
+		lang, err := template.LoadTemplates(name, dir)
+		if err != nil {
+			return nil, fmt.Errorf("template pack %s: %v", dir, err)
+		}
+		langs = append(langs, lang)
+	}
+	return langs, nil
+}
+
+// templatePack returns the name and directory of a template pack written as
+// "name=dir", or just "dir" to name it after the directory.
+func templatePack(s string) (name, dir string) {
+	if i := strings.Index(s, "="); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return "", s
+}
+
+// Build returns the build context files are selected with, nil for the host
+// platform without extra tags.
+func (p *Project) Build() *build.Context {
+	if len(p.Tags) == 0 && p.GOOS == "" && p.GOARCH == "" {
+		return nil
+	}
+	ctx := build.Default
+	ctx.BuildTags = p.Tags
+	if p.GOOS != "" {
+		ctx.GOOS = p.GOOS
+	}
+	if p.GOARCH != "" {
+		ctx.GOARCH = p.GOARCH
+	}
+	return &ctx
+}
+
+// resolve makes a relative path relative to base instead.
+func resolve(base, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
@@ -0,0 +1,130 @@
+package typewriter
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/natdm/typewriter/parse"
+	"github.com/natdm/typewriter/template"
+	"github.com/stretchr/testify/suite"
+)
+
+type ProjectTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestProjectTestSuite(t *testing.T) {
+	suite.Run(t, new(ProjectTestSuite))
+}
+
+func (s *ProjectTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "typewriter")
+	s.Require().NoError(err)
+	s.dir = dir
+}
+
+func (s *ProjectTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *ProjectTestSuite) write(name, content string) string {
+	path := filepath.Join(s.dir, name)
+	s.Require().NoError(ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func (s *ProjectTestSuite) TestYAML() {
+	s.write("typewriter.yaml", `
+dir: ./models
+exclude: ["*_gen.go"]
+types:
+  uuid.UUID: string
+naming: camel
+unions:
+  Shape:
+    discriminator: kind
+    variants: [Circle, Square=square]
+ts:
+  export: true
+targets:
+  - lang: ts
+    out: web/models.ts
+    types:
+      time.Time: string
+  - lang: flow
+`)
+	path, err := FindProject(s.dir)
+	s.Require().NoError(err)
+	p, err := LoadProject(path)
+	s.Require().NoError(err)
+
+	s.Equal(filepath.Join(s.dir, "models"), p.Dir)
+	s.True(p.Recursive, "recursive defaults to true")
+	s.Equal([]string{"*_gen.go"}, p.Exclude)
+	s.Require().Len(p.Targets, 2)
+	s.Equal(filepath.Join(s.dir, "web/models.ts"), p.Targets[0].Out)
+	s.Equal("", p.Targets[1].Out)
+	s.Equal(map[string]parse.Union{"Shape": {Discriminator: "kind", Variants: []string{"Circle", "Square=square"}}}, p.Unions)
+
+	opts := p.Options(p.Targets[0])
+	s.True(opts.TS.Export)
+	s.Equal(template.NamingCamel, opts.Naming)
+	s.Equal(map[string]string{"uuid.UUID": "string", "time.Time": "string"}, opts.Types)
+	s.Equal(map[string]string{"uuid.UUID": "string"}, p.Options(p.Targets[1]).Types)
+}
+
+func (s *ProjectTestSuite) TestJSON() {
+	s.write(".typewriter.json", `{"recursive": false, "flow": {"exact": true}, "targets": [{"lang": "flow"}]}`)
+	path, err := FindProject(s.dir)
+	s.Require().NoError(err)
+	p, err := LoadProject(path)
+	s.Require().NoError(err)
+	s.False(p.Recursive)
+	s.True(p.Flow.Exact)
+	s.Equal(s.dir, p.Dir, "dir defaults to the project file's directory")
+	s.Nil(p.Build(), "the host platform by default")
+}
+
+func (s *ProjectTestSuite) TestBuild() {
+	p, err := LoadProject(s.write("typewriter.yaml", "tags: [pro]\ngoos: windows\n"))
+	s.Require().NoError(err)
+	ctx := p.Build()
+	s.Require().NotNil(ctx)
+	s.Equal([]string{"pro"}, ctx.BuildTags)
+	s.Equal("windows", ctx.GOOS)
+	s.Equal(build.Default.GOARCH, ctx.GOARCH)
+}
+
+func (s *ProjectTestSuite) TestTemplates() {
+	s.Require().NoError(os.Mkdir(filepath.Join(s.dir, "ts"), 0755))
+	s.write("ts/basic.tmpl", "{{.Type}}")
+	p, err := LoadProject(s.write("typewriter.yaml", "templates: [project-pack=ts]\n"))
+	s.Require().NoError(err)
+	s.Equal([]string{"project-pack=" + filepath.Join(s.dir, "ts")}, p.Templates)
+	langs, err := p.LoadTemplates()
+	s.Require().NoError(err)
+	s.Equal("project-pack", langs[0].String())
+
+	// Packs are named after their directory by default, here a built-in language.
+	p.Templates = []string{filepath.Join(s.dir, "ts")}
+	_, err = p.LoadTemplates()
+	s.Error(err)
+}
+
+func (s *ProjectTestSuite) TestInvalid() {
+	_, err := LoadProject(s.write("typewriter.yaml", "lang: flow\n"))
+	s.Error(err, "unknown keys")
+
+	_, err = LoadProject(s.write("typewriter.yaml", "naming: kebab\n"))
+	s.Error(err)
+}
+
+func (s *ProjectTestSuite) TestNotFound() {
+	path, err := FindProject(s.dir)
+	s.NoError(err)
+	s.Equal("", path)
+}
@@ -0,0 +1,144 @@
+package template
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/natdm/typewriter/ir"
+)
+
+// This file contains the JSON decoders drawn for languages without structural
+// types, such as Elm, where a union can only be read from JSON by a decoder
+// of every type it is made of.
+
+// decoder is the decoder of a type, as seen by the Decoder fragment.
+type decoder struct {
+	// Name is the name of the decoded type.
+	Name string
+
+	// Type is the decoded type.
+	Type Templater
+}
+
+// drawDecoder draws the decoder of a declaration.
+func drawDecoder(w io.Writer, d *ir.Decl, lang Language, opts Options) error {
+	p := packageType(d, opts.Types)
+	return newTemplate(lang, fragments(lang).Decoder, opts).Execute(w, decoder{Name: p.Name, Type: p.Type})
+}
+
+// decoded returns the names of the types of t drawn with a decoder, for
+// languages with a Decoder fragment: every union, and every type it refers
+// to, directly or not.
+func decoded(t map[string]*ir.Decl, lang Language) map[string]bool {
+	if fragments(lang).Decoder == "" {
+		return nil
+	}
+	var unions []*ir.Decl
+	for _, d := range t {
+		if d.Type != nil && d.Type.Kind == ir.Union {
+			unions = append(unions, d)
+		}
+	}
+	reached := ir.Reachable(unions, func(_ *ir.Decl, ref string) []*ir.Decl {
+		if name, ok := ir.Resolve(t, ref); ok {
+			return []*ir.Decl{t[name]}
+		}
+		return nil
+	})
+	names := make(map[string]bool)
+	for name, d := range t {
+		if reached[d] {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+var (
+	elmInt   = regexp.MustCompile("^(" + goInt + ")$")
+	elmFloat = regexp.MustCompile("^(" + goFloat + ")$")
+)
+
+// elmDecoder returns the Json.Decode decoder of the type t named name, for a
+// declaration indented by four spaces. Types that are not built in are
+// decoded by a decoder named after them, such as decodeEvent.
+func elmDecoder(name string, t Templater, opts Options) string {
+	switch x := t.(type) {
+	case *Struct:
+		if len(x.Fields) == 0 {
+			return "Json.Decode.succeed {}"
+		}
+		lines := []string{"Json.Decode.succeed " + name}
+		for _, f := range x.Fields {
+			key, _ := f.jsonName(opts)
+			lines = append(lines, fmt.Sprintf("|> Json.Decode.map2 (|>) (Json.Decode.field %q %s)", key, elmTypeDecoder(f.override(f.Type))))
+		}
+		return strings.Join(lines, "\n        ")
+	case *Union:
+		return elmUnionDecoder(x)
+	}
+	return elmTypeDecoder(t)
+}
+
+// elmUnionDecoder returns the decoder of a union, by its discriminator or
+// else by trying every variant in turn.
+func elmUnionDecoder(u *Union) string {
+	var b strings.Builder
+	if u.Discriminator != "" {
+		fmt.Fprintf(&b, "Json.Decode.field %q Json.Decode.string\n", u.Discriminator)
+		b.WriteString("        |> Json.Decode.andThen\n")
+		b.WriteString("            (\\tag ->\n")
+		b.WriteString("                case tag of\n")
+		for _, v := range u.Variants {
+			fmt.Fprintf(&b, "                    %s ->\n", v.Tag)
+			fmt.Fprintf(&b, "                        Json.Decode.map %s%s decode%s\n\n", u.Name, v.Name, v.Name)
+		}
+		b.WriteString("                    _ ->\n")
+		fmt.Fprintf(&b, "                        Json.Decode.fail (\"unknown %s \" ++ tag)\n", u.Name)
+		b.WriteString("            )")
+		return b.String()
+	}
+	b.WriteString("Json.Decode.oneOf")
+	for i, v := range u.Variants {
+		open := ","
+		if i == 0 {
+			open = "["
+		}
+		fmt.Fprintf(&b, "\n        %s Json.Decode.map %s%s decode%s", open, u.Name, v.Name, v.Name)
+	}
+	b.WriteString("\n        ]")
+	return b.String()
+}
+
+// elmTypeDecoder returns the decoder of a type as drawn in Elm.
+func elmTypeDecoder(t Templater) string {
+	switch x := t.(type) {
+	case *Array:
+		return "(Json.Decode.list " + elmTypeDecoder(x.Type) + ")"
+	case *Map:
+		return "(Json.Decode.dict " + elmTypeDecoder(x.Value) + ")"
+	case *Enum:
+		return elmTypeDecoder(&Basic{Type: x.Type})
+	case *Union:
+		return "decode" + x.Name
+	case *Generic:
+		return elmTypeDecoder(&Basic{Type: x.Type})
+	case *Basic:
+		switch {
+		case x.Type == "string":
+			return "Json.Decode.string"
+		case x.Type == "bool":
+			return "Json.Decode.bool"
+		case elmInt.MatchString(x.Type):
+			return "Json.Decode.int"
+		case elmFloat.MatchString(x.Type):
+			return "Json.Decode.float"
+		case x.Type == EmptyInterface || x.Type == NestedStruct:
+			return "Json.Decode.value"
+		}
+		return "decode" + x.Type[strings.LastIndex(x.Type, ".")+1:]
+	}
+	return "Json.Decode.value"
+}
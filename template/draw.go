@@ -1,18 +1,39 @@
 package template
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 
 	"sort"
 
+	"github.com/natdm/typewriter/ir"
+
 	log "github.com/sirupsen/logrus"
 )
 
-// Draw draws all types to a writer.
-func Draw(t map[string]*PackageType, out io.Writer, lang Language, verbose bool) (int, error) {
-	if err := Header(out, lang); err != nil {
+// Draw draws all types to a writer. Every drawn type is logged at debug level.
+func Draw(t map[string]*ir.Decl, out io.Writer, lang Language, opts Options, logger log.FieldLogger) (int, error) {
+	if !lang.registered() {
+		return 0, fmt.Errorf("unknown language: %s", lang)
+	}
+	if err := Header(out, lang, opts); err != nil {
 		return 0, err
 	}
+	decoders := decoded(t, lang)
+	if len(decoders) > 0 {
+		if err := newTemplate(lang, fragments(lang).DecoderHeader, opts).Execute(out, nil); err != nil {
+			return 0, err
+		}
+	}
+
+	// Declarations in an ambient block are drawn first so they can be indented.
+	body := out
+	ambient := lang == Typescript && opts.TS.Ambient()
+	buf := bytes.Buffer{}
+	if ambient {
+		body = &buf
+	}
 
 	keys := make([]string, 0, len(t))
 	for k := range t {
@@ -20,17 +41,50 @@ func Draw(t map[string]*PackageType, out io.Writer, lang Language, verbose bool)
 	}
 	sort.Strings(keys)
 
-	for _, k := range keys {
-		v := t[k]
-		if err := v.Template(out, lang); err != nil {
+	if err := drawTypes(t, keys, decoders, body, lang, opts, logger); err != nil {
+		return 0, err
+	}
+
+	if ambient {
+		if _, err := out.Write(indent(buf.Bytes())); err != nil {
 			return 0, err
 		}
-		if err := Raw(out, "\n"); err != nil && verbose {
-			log.WithField("type", k).Warn("unable to create new line")
+	}
+	if err := Footer(out, lang, opts); err != nil {
+		return 0, err
+	}
+	return len(keys), nil
+}
+
+// drawTypes draws the types named by keys, in order, with the decoders of
+// the ones in decoders.
+func drawTypes(t map[string]*ir.Decl, keys []string, decoders map[string]bool, w io.Writer, lang Language, opts Options, logger log.FieldLogger) error {
+	for _, k := range keys {
+		if err := packageType(t[k], opts.Types).Template(w, lang, opts); err != nil {
+			return fmt.Errorf("%s: %v", k, err)
+		}
+		if err := Raw(w, "\n"); err != nil {
+			logger.WithField("type", k).Warn("unable to create new line")
 		}
-		if verbose {
-			log.Infof("created type: %s", k)
+		if decoders[k] {
+			if err := drawDecoder(w, t[k], lang, opts); err != nil {
+				return err
+			}
 		}
+		logger.Debugf("created type: %s", k)
 	}
-	return len(keys), nil
+	return nil
+}
+
+// indent prefixes every non-empty line with a tab.
+func indent(bs []byte) []byte {
+	lines := bytes.SplitAfter(bs, []byte{'\n'})
+	out := make([]byte, 0, len(bs)+len(lines))
+	for _, l := range lines {
+		if len(bytes.TrimSpace(l)) > 0 {
+			out = append(out, '\t')
+		}
+		out = append(out, l...)
+	}
+	return out
 }
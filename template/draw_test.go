@@ -0,0 +1,248 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/natdm/typewriter/ir"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+type DrawTestSuite struct {
+	suite.Suite
+}
+
+func TestDrawTestSuite(t *testing.T) {
+	suite.Run(t, new(DrawTestSuite))
+}
+
+func irBasic(name string, pointer bool) *ir.Type {
+	return &ir.Type{Kind: ir.Basic, Name: name, Pointer: pointer}
+}
+
+func (s *DrawTestSuite) draw(types map[string]*ir.Decl, lang Language) string {
+	buf := new(bytes.Buffer)
+	_, err := Draw(types, buf, lang, Options{}, log.New())
+	s.Require().NoError(err)
+	return buf.String()
+}
+
+func (s *DrawTestSuite) TestDrawIsRepeatable() {
+	types := map[string]*ir.Decl{
+		"Example": {Name: "Example", Type: &ir.Type{Kind: ir.Struct, Fields: []*ir.Field{
+			{Name: "Pointer", Type: irBasic("Event", true), Tag: `json:"event_pointer"`},
+			{Name: "Invalid", Type: irBasic("string", false), Tag: `json:"kebab-case"`},
+			{Name: "Override", Type: irBasic("int", false), Tag: `json:"override" tw:"Custom,true"`},
+		}}},
+	}
+
+	ts := s.draw(types, Typescript)
+	flow := s.draw(types, Flow)
+	s.Equal(ts, s.draw(types, Typescript))
+	s.Equal(flow, s.draw(types, Flow))
+	s.Contains(ts, "\tevent_pointer?: Event,\n")
+	s.Contains(flow, "\t\"kebab-case\": string,\n")
+
+	_, err := Draw(types, new(bytes.Buffer), JSDoc, Options{}, log.New())
+	s.EqualError(err, `Example: field Invalid: "kebab-case" is not a valid JSDoc property name, rename it in its json tag`)
+	s.Equal("Pointer", types["Example"].Type.Fields[0].Name)
+	s.Equal("int", types["Example"].Type.Fields[2].Type.Name)
+}
+
+func (s *DrawTestSuite) TestDrawEnum() {
+	types := map[string]*ir.Decl{
+		"Color": {Name: "Color", Type: &ir.Type{Kind: ir.Enum, Name: "string", Values: []*ir.EnumValue{
+			{Name: "Red", Value: `"red"`},
+			{Name: "Green", Value: `"green"`},
+		}}},
+	}
+
+	s.Contains(s.draw(types, Typescript), "\ntype Color = string\n")
+	s.Contains(s.draw(types, Flow), "\nexport type Color = string\n")
+
+	unions := func(lang Language) string {
+		buf := new(bytes.Buffer)
+		_, err := Draw(types, buf, lang, Options{EnumUnions: true}, log.New())
+		s.Require().NoError(err)
+		return buf.String()
+	}
+	s.Contains(unions(Typescript), "\ntype Color = \"red\" | \"green\"\n")
+	s.Contains(unions(Flow), "\nexport type Color = \"red\" | \"green\"\n")
+	s.Contains(unions(JSDoc), " * @typedef {\"red\"|\"green\"} Color\n")
+	s.Contains(unions(Elm), "\ntype alias Color :  string\n")
+}
+
+func (s *DrawTestSuite) TestDrawUnion() {
+	variants := []*ir.Variant{{Type: irBasic("Circle", false), Tag: "circle"}, {Type: irBasic("shapes.Square", false), Tag: "square"}}
+	types := map[string]*ir.Decl{
+		"Shape":    {Name: "Shape", Type: &ir.Type{Kind: ir.Union, Discriminator: "kind", Variants: variants}},
+		"Drawable": {Name: "Drawable", Type: &ir.Type{Kind: ir.Union, Variants: variants}},
+	}
+
+	s.Equal(`// Automatically generated by typewriter. Do not edit.
+// http://www.github.com/natdm/typewriter
+
+
+type Drawable = Circle | shapes.Square
+
+type Shape = ({ kind: "circle" } & Circle) | ({ kind: "square" } & shapes.Square)
+`, s.draw(types, Typescript))
+	s.Equal(`// @flow
+// Automatically generated by typewriter. Do not edit.
+// http://www.github.com/natdm/typewriter
+
+
+export type Drawable = Circle | shapes.Square
+
+export type Shape = { ...Circle, kind: "circle" } | { ...shapes.Square, kind: "square" }
+`, s.draw(types, Flow))
+	s.Equal(`// Automatically generated by typewriter. Do not edit.
+// http://www.github.com/natdm/typewriter
+
+
+/**
+ * @typedef {Circle|shapes.Square} Drawable
+ */
+
+/**
+ * @typedef {Circle|shapes.Square} Shape
+ */
+`, s.draw(types, JSDoc))
+
+	// Elm unions are read by a decoder of every type they refer to.
+	types["Circle"] = &ir.Decl{Name: "Circle", Type: &ir.Type{Kind: ir.Struct, Fields: []*ir.Field{
+		{Name: "Radius", Type: irBasic("float64", false), Tag: `json:"radius"`},
+		{Name: "Tags", Type: &ir.Type{Kind: ir.Array, Elem: irBasic("string", false)}},
+	}}}
+	types["Unrelated"] = &ir.Decl{Name: "Unrelated", Type: &ir.Type{Kind: ir.Struct, Fields: []*ir.Field{
+		{Name: "Name", Type: irBasic("string", false)},
+	}}}
+	s.Equal(`-- Automatically generated by typewriter. Do not edit.
+-- http://www.github.com/natdm/typewriter
+
+import Json.Decode
+
+type alias Circle : 
+{	radius : Float,
+	Tags : List string
+}
+
+
+decodeCircle : Json.Decode.Decoder Circle
+decodeCircle =
+    Json.Decode.succeed Circle
+        |> Json.Decode.map2 (|>) (Json.Decode.field "radius" Json.Decode.float)
+        |> Json.Decode.map2 (|>) (Json.Decode.field "Tags" (Json.Decode.list Json.Decode.string))
+
+type Drawable
+    = DrawableCircle Circle
+    | DrawableSquare Square
+
+
+decodeDrawable : Json.Decode.Decoder Drawable
+decodeDrawable =
+    Json.Decode.oneOf
+        [ Json.Decode.map DrawableCircle decodeCircle
+        , Json.Decode.map DrawableSquare decodeSquare
+        ]
+
+type Shape
+    = ShapeCircle Circle
+    | ShapeSquare Square
+
+
+decodeShape : Json.Decode.Decoder Shape
+decodeShape =
+    Json.Decode.field "kind" Json.Decode.string
+        |> Json.Decode.andThen
+            (\tag ->
+                case tag of
+                    "circle" ->
+                        Json.Decode.map ShapeCircle decodeCircle
+
+                    "square" ->
+                        Json.Decode.map ShapeSquare decodeSquare
+
+                    _ ->
+                        Json.Decode.fail ("unknown Shape " ++ tag)
+            )
+
+type alias Unrelated : 
+{	Name : string
+}
+`, s.draw(types, Elm))
+
+	buf := new(bytes.Buffer)
+	_, err := Draw(map[string]*ir.Decl{"Shape": types["Shape"]}, buf, Typescript, Options{Types: map[string]string{"shapes.Square": "Box"}}, log.New())
+	s.Require().NoError(err)
+	s.Contains(buf.String(), "& Box)")
+}
+
+func (s *DrawTestSuite) TestDrawGenerics() {
+	types := map[string]*ir.Decl{
+		"Page": {
+			Name:       "Page",
+			TypeParams: []*ir.TypeParam{{Name: "T", Constraint: "any"}},
+			Type: &ir.Type{Kind: ir.Struct, Fields: []*ir.Field{
+				{Name: "Items", Type: &ir.Type{Kind: ir.Array, Elem: irBasic("T", false)}, Tag: `json:"items"`},
+			}},
+		},
+		"Users": {
+			Name: "Users",
+			Type: &ir.Type{Kind: ir.Basic, Name: "Page", Args: []*ir.Type{irBasic("int", false)}},
+		},
+	}
+
+	ts := s.draw(types, Typescript)
+	s.Contains(ts, "\ntype Page<T> = {\n\titems: T[],\n}\n")
+	s.Contains(ts, "\ntype Users = Page<number>\n")
+
+	flow := s.draw(types, Flow)
+	s.Contains(flow, "\nexport type Page<T> = {\n\titems: T[],\n}\n")
+	s.Contains(flow, "\nexport type Users = Page<number>\n")
+
+	s.Contains(s.draw(types, JSDoc), " * @template T\n * @typedef {Object} Page\n")
+}
+
+func (s *DrawTestSuite) TestDrawTypeMappings() {
+	types := map[string]*ir.Decl{
+		"Account": {Name: "Account", Type: &ir.Type{Kind: ir.Struct, Fields: []*ir.Field{
+			{Name: "ID", Type: irBasic("uuid.UUID", false), Tag: `json:"id"`},
+			{Name: "Owners", Type: &ir.Type{Kind: ir.Array, Elem: irBasic("uuid.UUID", true)}, Tag: `json:"owners"`},
+			{Name: "Created", Type: irBasic("time.Time", false), Tag: `json:"created" tw:"number"`},
+		}}},
+	}
+	opts := Options{Types: map[string]string{"uuid.UUID": "string", "time.Time": "string"}}
+
+	buf := new(bytes.Buffer)
+	_, err := Draw(types, buf, Flow, opts, log.New())
+	s.Require().NoError(err)
+	s.Contains(buf.String(), "\tid: string,\n")
+	s.Contains(buf.String(), "\towners: Array<?string>,\n")
+	s.Contains(buf.String(), "\tcreated: number,\n", "tw tags win over mappings")
+	s.Equal("uuid.UUID", types["Account"].Type.Fields[0].Type.Name)
+}
+
+func (s *DrawTestSuite) TestDrawNaming() {
+	types := map[string]*ir.Decl{
+		"User": {Name: "User", Type: &ir.Type{Kind: ir.Struct, Fields: []*ir.Field{
+			{Name: "UserID", Type: irBasic("int", false)},
+			{Name: "HTTPServer", Type: irBasic("string", false)},
+			{Name: "Tagged", Type: irBasic("string", false), Tag: `json:"Tagged"`},
+		}}},
+	}
+
+	for naming, want := range map[Naming][]string{
+		NamingGo:    {"UserID", "HTTPServer"},
+		NamingCamel: {"userID", "httpServer"},
+		NamingSnake: {"user_id", "http_server"},
+	} {
+		buf := new(bytes.Buffer)
+		_, err := Draw(types, buf, Typescript, Options{Naming: naming}, log.New())
+		s.Require().NoError(err)
+		s.Contains(buf.String(), "\t"+want[0]+": number,\n")
+		s.Contains(buf.String(), "\t"+want[1]+": string,\n")
+		s.Contains(buf.String(), "\tTagged: string,\n")
+	}
+}
@@ -6,114 +6,268 @@ import (
 
 // This file contains all the dynamic template fragments for each language.
 
-// language templates
-var templates = map[Language]langTemplates{
-	Elm:        elmTemplates,
-	Flow:       flowTemplates,
-	Typescript: tsTemplates,
+// Fragments are the templates a Language draws every type with. Each is a
+// text/template with access to the shared template functions, the language's
+// `updateType` conversion function and the run's `opts`.
+type Fragments struct {
+	Header          string
+	ArrayOpen       string
+	ArrayClose      string
+	ArrayShortOpen  string
+	ArrayShortClose string
+	Basic           string
+	FieldDocComment string
+	Declaration     string
+	FieldClose      string
+	LastFieldClose  string
+	FieldName       string
+	MapClose        string
+	MapKey          string
+	MapValue        string
+	StructClose     string
+	StructOpen      string
+	TimeType        string
+
+	// Footer, when set, closes the file after the last type.
+	Footer string
+
+	// Typedef, when set, replaces Declaration for languages that name a type
+	// after its definition. It receives the rendered type as .Body.
+	Typedef string
+
+	// Property, when set, replaces FieldName for languages that name a field
+	// after its type. It receives the rendered field type as .Body.
+	Property string
+
+	// Enum draws a type limited to the JSON encoded .Values, with
+	// Options.EnumUnions. Without it, enums are drawn as their underlying
+	// basic .Type.
+	Enum string
+
+	// Union draws an interface as one of its .Variants, each with its
+	// rendered .Body, bare .Name and JSON encoded .Tag, told apart by the
+	// .Discriminator field, quoted as .Key where needed. The interface is
+	// named .Name. Without it, unions are drawn as the empty interface.
+	Union string
+
+	// Decoder, when set, draws the JSON decoder of every union, and of every
+	// type a union refers to, directly or not, after the type. It is for
+	// languages reading unions by hand, and receives the type's .Name and .Type.
+	Decoder string
+
+	// DecoderHeader is drawn after the Header, and Module, of files with
+	// decoders, such as the import of the decoding library.
+	DecoderHeader string
+
+	// FileName names the file drawn for a Go package or type, given as `.`,
+	// when output is split into files. Languages without it cannot be split.
+	FileName string
+
+	// Module, when set, is drawn after the Header of every split file. It
+	// receives the file's .Module and its type .Names.
+	Module string
+
+	// Import is drawn for every file a split file references types from. It
+	// receives the imported file's .Path, relative and without extension,
+	// its .Module and the imported type .Names.
+	Import string
+
+	// Index, when set, is drawn once for every split file into an index file
+	// re-exporting them all. It receives the same data as Import.
+	Index string
 }
 
-type langTemplates struct {
-	header          string
-	arrayOpen       string
-	arrayClose      string
-	arrayShortOpen  string
-	arrayShortClose string
-	basic           string
-	fieldDocComment string
-	declaration     string
-	fieldClose      string
-	lastFieldClose  string
-	fieldName       string
-	mapClose        string
-	mapKey          string
-	mapValue        string
-	structClose     string
-	structOpen      string
-	timeType        string
+// byName maps the name of every fragment to its template.
+func (f *Fragments) byName() map[string]*string {
+	return map[string]*string{
+		"header":          &f.Header,
+		"arrayOpen":       &f.ArrayOpen,
+		"arrayClose":      &f.ArrayClose,
+		"arrayShortOpen":  &f.ArrayShortOpen,
+		"arrayShortClose": &f.ArrayShortClose,
+		"basic":           &f.Basic,
+		"fieldDocComment": &f.FieldDocComment,
+		"declaration":     &f.Declaration,
+		"fieldClose":      &f.FieldClose,
+		"lastFieldClose":  &f.LastFieldClose,
+		"fieldName":       &f.FieldName,
+		"mapClose":        &f.MapClose,
+		"mapKey":          &f.MapKey,
+		"mapValue":        &f.MapValue,
+		"structClose":     &f.StructClose,
+		"structOpen":      &f.StructOpen,
+		"timeType":        &f.TimeType,
+		"footer":          &f.Footer,
+		"typedef":         &f.Typedef,
+		"property":        &f.Property,
+		"enum":            &f.Enum,
+		"union":           &f.Union,
+		"decoder":         &f.Decoder,
+		"decoderHeader":   &f.DecoderHeader,
+		"fileName":        &f.FileName,
+		"module":          &f.Module,
+		"import":          &f.Import,
+		"index":           &f.Index,
+	}
 }
 
 // newTemplate returns the template string for a language and a string
-func newTemplate(tpl string) *template.Template {
+func newTemplate(lang Language, tpl string, opts Options) *template.Template {
 	return template.Must(template.New("dummy").
 		Funcs(funcMap).
+		Funcs(lookup(lang).funcs).
+		Funcs(template.FuncMap{"opts": func() Options { return opts }}).
 		Parse(tpl))
 }
 
-var elmTemplates = langTemplates{
-	header: `-- Automatically generated by typewriter. Do not edit.
+var elmTemplates = Fragments{
+	Header: `-- Automatically generated by typewriter. Do not edit.
 -- http://www.github.com/natdm/typewriter
 
 `,
-	arrayOpen:       ` List`,
-	arrayClose:      ``,
-	arrayShortOpen:  ` List`,
-	arrayShortClose: ``,
-	basic:           ` {{updateElmType .Type}}`,
-	fieldDocComment: `{{elmMultilineComment .DocComment 1}}`,
-	declaration: `
-{{elmMultilineComment .Comment 0}}type alias {{.Name}} : `,
-	fieldClose: `,{{elmComment .LineComment}}
+	ArrayOpen:       ` List`,
+	ArrayClose:      ``,
+	ArrayShortOpen:  ` List`,
+	ArrayShortClose: ``,
+	Basic:           ` {{updateElmType .Type}}`,
+	FieldDocComment: `{{elmMultilineComment .DocComment 1}}`,
+	Declaration: `
+{{elmMultilineComment .Comment 0}}{{if .IsUnion}}type {{.Name}}{{else}}type alias {{.Name}} : {{end}}`,
+	FieldClose: `,{{elmComment .LineComment}}
 `,
-	lastFieldClose: `{{elmComment .LineComment}}
+	LastFieldClose: `{{elmComment .LineComment}}
 `, // Elm has no trailing comma support
-	fieldName: `	{{.Name}} :`,
-	mapClose:    ``,
-	mapKey:      `Dict `,
-	mapValue:    ` `,
-	structClose: `}`,
-	structOpen: `
+	FieldName:   `	{{.Name}} :`,
+	MapClose:    ``,
+	MapKey:      `Dict `,
+	MapValue:    ` `,
+	StructClose: `}`,
+	StructOpen: `
 {`,
-	timeType: "Date",
+	TimeType: "Date",
+	Enum:     ` {{updateElmType .Type}}`,
+	// Elm has no structural unions: interfaces are custom types, of variants
+	// named after the types in the same module, read by a decoder.
+	Union: `
+    = {{range $i, $v := .Variants}}{{if $i}}
+    | {{end}}{{$.Name}}{{.Name}} {{.Name}}{{end}}`,
+	Decoder: `
+
+decode{{.Name}} : Json.Decode.Decoder {{.Name}}
+decode{{.Name}} =
+    {{elmDecoder .Name .Type (opts)}}
+`,
+	DecoderHeader: `import Json.Decode
+`,
+	FileName: `{{title .}}.elm`,
+	Module: `module {{.Module}} exposing (..)
+
+`,
+	Import: `import {{.Module}} exposing ({{join .Names ", "}}{{range .Decoders}}, decode{{.}}{{end}})
+`,
 }
 
-var flowTemplates = langTemplates{
-	header: `// @flow
+var flowTemplates = Fragments{
+	Header: `// @flow
 // Automatically generated by typewriter. Do not edit.
 // http://www.github.com/natdm/typewriter
 
 `,
-	arrayOpen:       `Array<`,
-	arrayClose:      `>`,
-	arrayShortOpen:  ``,
-	arrayShortClose: `[]`,
-	basic:           `{{if .Pointer}}?{{end}}{{updateFlowType .Type}}`,
-	fieldDocComment: `{{flowMultilineComment .DocComment 1}}`,
-	declaration: `
-{{flowMultilineComment .Comment 0}}export type {{.Name}} = `,
-	fieldClose: `,{{flowComment .LineComment}}
+	ArrayOpen:       `Array<`,
+	ArrayClose:      `>`,
+	ArrayShortOpen:  ``,
+	ArrayShortClose: `[]`,
+	Basic:           `{{if .Pointer}}?{{end}}{{updateFlowType .Type}}{{with .TypeArgs}}<{{join . ", "}}>{{end}}`,
+	FieldDocComment: `{{flowMultilineComment .DocComment 1}}`,
+	Declaration: `
+{{flowMultilineComment .Comment 0}}export type {{.Name}}{{with .TypeParams}}<{{join . ", "}}>{{end}} = `,
+	FieldClose: `,{{flowComment .LineComment}}
+`,
+	FieldName: `	{{if (opts).Flow.Covariant}}+{{end}}{{.Name}}: `,
+	MapClose:  ` }`,
+	MapKey:    `{ [key: `,
+	MapValue:  `]: `,
+	StructClose: `{{if .Inexact}}	...
+{{end}}{{if (opts).Flow.IsExact .}}|{{end}}}{{if (opts).Flow.ReadOnly}}>{{end}}`,
+	// Exact objects cannot be intersected, so they spread embedded types instead.
+	StructOpen: `{{if (opts).Flow.ReadOnly}}$ReadOnly<{{end}}{{if (opts).Flow.IsExact .}}{{"{|"}}
+{{range .Embedded}}	...{{.}},
+{{end}}{{else}}{{range .Embedded}}{{.}} & {{end}}{{"{"}}
+{{end}}`,
+	TimeType: "Date",
+	Enum:     `{{join .Values " | "}}`,
+	Union:    `{{range $i, $v := .Variants}}{{if $i}} | {{end}}{{if $.Discriminator}}{ ...{{.Body}}, {{$.Key}}: {{.Tag}} }{{else}}{{.Body}}{{end}}{{end}}`,
+	FileName: `{{.}}.js`,
+	Import: `import type { {{join .Names ", "}} } from '{{.Path}}'
 `,
-	fieldName: `	{{.Name}}: `,
-	mapClose:    ` }`,
-	mapKey:      `{ [key: `,
-	mapValue:    `]: `,
-	structClose: `{{ range .Embedded}}{{.}} & {{end}}{{if .Strict}}|}{{else}}}{{end}}`,
-	structOpen: `{{"{"}}{{if .Strict}}|{{end}}
+	Index: `export type { {{join .Names ", "}} } from '{{.Path}}'
 `,
-	timeType: "Date",
 }
 
-var tsTemplates = langTemplates{
-	header: `// Automatically generated by typewriter. Do not edit.
+var tsTemplates = Fragments{
+	Header: `// Automatically generated by typewriter. Do not edit.
 // http://www.github.com/natdm/typewriter
 
+{{with (opts).TS.Namespace}}declare namespace {{.}} {{"{"}}
+{{end}}{{with (opts).TS.Module}}declare module "{{.}}" {{"{"}}
+{{end}}`,
+	Footer: `{{if (opts).TS.Ambient}}}
+{{end}}`,
+	ArrayOpen:       `Array<`,
+	ArrayClose:      `>`,
+	ArrayShortOpen:  ``,
+	ArrayShortClose: `[]`,
+	Basic:           `{{updateTSType .Type}}{{with .TypeArgs}}<{{join . ", "}}>{{end}}{{if .Pointer}} | undefined{{end}}`,
+	FieldDocComment: `{{tsMultilineComment .DocComment 1}}`,
+	Declaration: `
+{{tsMultilineComment .Comment 0}}{{if (opts).TS.Export}}export {{end}}
+{{- if (opts).TS.DeclaresInterface .Type}}interface {{.Name}}{{with .TypeParams}}<{{join . ", "}}>{{end}}
+{{- range $i, $e := .Type.Embedded}}{{if $i}}, {{else}} extends {{end}}{{$e}}{{end}} {{else}}type {{.Name}}{{with .TypeParams}}<{{join . ", "}}>{{end}} = {{end}}`,
+	FieldClose: `,{{tsComment .LineComment}}
 `,
-	arrayOpen:       `Array<`,
-	arrayClose:      `>`,
-	arrayShortOpen:  ``,
-	arrayShortClose: `[]`,
-	basic:           `{{updateTSType .Type}}{{if .Pointer}} | undefined{{end}}`,
-	fieldDocComment: `{{tsMultilineComment .DocComment 1}}`,
-	declaration: `
-{{tsMultilineComment .Comment 0}}type {{.Name}} = `,
-	fieldClose: `,{{tsComment .LineComment}}
+	FieldName:   `	{{if (opts).TS.Readonly}}readonly {{end}}{{.Name}}{{if .Type.IsPointer}}?{{end}}: `,
+	MapClose:    ` }`,
+	MapKey:      `{ [key: `,
+	MapValue:    `]: `,
+	StructClose: `}`,
+	StructOpen: `{{if not ((opts).TS.DeclaresInterface .)}}{{ range .Embedded}}{{ . }} & {{end}}{{end}}{
 `,
-	fieldName: `	{{.Name}}{{if .Type.IsPointer}}?{{end}}: `,
-	mapClose:    ` }`,
-	mapKey:      `{ [key: `,
-	mapValue:    `]: `,
-	structClose: `}`,
-	structOpen: `{{ range .Embedded}}{{ . }} & {{end}}{
+	TimeType: "Date",
+	Enum:     `{{join .Values " | "}}`,
+	Union:    `{{range $i, $v := .Variants}}{{if $i}} | {{end}}{{if $.Discriminator}}({ {{$.Key}}: {{.Tag}} } & {{.Body}}){{else}}{{.Body}}{{end}}{{end}}`,
+	FileName: `{{.}}.ts`,
+	Import: `import type { {{join .Names ", "}} } from "{{.Path}}"
+`,
+	Index: `export * from "{{.Path}}"
+`,
+}
+
+var jsdocTemplates = Fragments{
+	Header: `// Automatically generated by typewriter. Do not edit.
+// http://www.github.com/natdm/typewriter
+
 `,
-	timeType: "Date",
+	ArrayOpen:       `Array<`,
+	ArrayClose:      `>`,
+	ArrayShortOpen:  ``,
+	ArrayShortClose: `[]`,
+	Basic:           `{{if .Pointer}}?{{end}}{{updateJSDocType .Type}}{{with .TypeArgs}}<{{join . ", "}}>{{end}}`,
+	Typedef: `
+/**
+{{jsdocComment .Comment 0}}{{range .TypeParams}} * @template {{.}}
+{{end}} * @typedef {{"{"}}{{if .IsStruct}}Object{{else}}{{.Body}}{{end}}} {{.Name}}
+{{if .IsStruct}}{{.Body}}{{end}} */`,
+	Property:    ` * @property {{"{"}}{{.Body}}} {{if .Optional}}[{{.Name}}]{{else}}{{.Name}}{{end}}{{jsdocDescription (or .DocComment .LineComment)}}`,
+	FieldClose:  "\n",
+	MapClose:    `>`,
+	MapKey:      `Object<`,
+	MapValue:    `, `,
+	StructClose: ``,
+	StructOpen:  ``,
+	TimeType:    "Date",
+	Enum:        `{{join .Values "|"}}`,
+	Union:       `{{range $i, $v := .Variants}}{{if $i}}|{{end}}{{.Body}}{{end}}`,
+	FileName:    `{{.}}.js`,
+	Import: `{{range .Names}}/** @typedef {import('{{$.Path}}').{{.}}} {{.}} */
+{{end}}`,
 }
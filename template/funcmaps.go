@@ -4,71 +4,81 @@ import (
 	"regexp"
 	"strings"
 	"text/template"
-)
-
-// Language is a parsable language
-// stringer -type=Language
-type Language int
+	"unicode"
+	"unicode/utf8"
 
-// languages
-const (
-	Typescript Language = iota
-	Flow
-	Elm
+	"github.com/natdm/typewriter/ir"
 )
 
 // custom types
 const (
-	EmptyInterface = "emptyIface"
-	NestedStruct   = "struct"
+	EmptyInterface = ir.EmptyInterface
+	NestedStruct   = ir.NestedStruct
 	TimeStruct     = "Date"
 )
 
 var funcMap = template.FuncMap{
-	"updateFlowType":       updateTypes(conversions[Flow]),
-	"updateElmType":        updateTypes(conversions[Elm]),
-	"updateTSType":         updateTypes(conversions[Typescript]),
+	"updateFlowType":       convert(Flow),
+	"updateElmType":        convert(Elm),
+	"updateTSType":         convert(Typescript),
+	"updateJSDocType":      convert(JSDoc),
 	"flowComment":          lineComment("//"),
 	"elmComment":           lineComment("--"),
 	"tsComment":            lineComment("//"),
 	"flowMultilineComment": multilineComment("//"),
 	"elmMultilineComment":  multilineComment("--"),
 	"tsMultilineComment":   multilineComment("//"),
+	"jsdocComment":         multilineComment(" *"),
+	"jsdocDescription":     jsdocDescription,
+	"elmDecoder":           elmDecoder,
+	"title":                title,
+	"join":                 strings.Join,
 }
 
 const goInt = "int64|int32|int16|int8|int|uint64|uint32|uint16|uint8|uint|byte|rune"
 const goFloat = "float32|float64|complex64|complex128"
 const goNumbers = goInt + "|" + goFloat
 
-func asWord(baseRegex string) *regexp.Regexp {
-	return regexp.MustCompile("\\b(" + baseRegex + ")\\b")
-}
-
-var conversions = map[Language]map[string]*regexp.Regexp{
-	Flow: map[string]*regexp.Regexp{
-		"any":     asWord(EmptyInterface),
-		"Object":  asWord(NestedStruct),
-		"Date":    asWord(TimeStruct),
-		"number":  asWord(goNumbers),
-		"boolean": asWord("bool"),
+// conversions are the type conversions of the built-in languages
+var conversions = map[Language]map[string]string{
+	Flow: map[string]string{
+		"any":     EmptyInterface,
+		"Object":  NestedStruct,
+		"Date":    TimeStruct,
+		"number":  goNumbers,
+		"boolean": "bool",
 	},
-	Typescript: map[string]*regexp.Regexp{
-		"any":     asWord(EmptyInterface),
-		"object":  asWord(NestedStruct),
-		"Date":    asWord(TimeStruct),
-		"number":  asWord(goNumbers),
-		"boolean": asWord("bool"),
+	Typescript: map[string]string{
+		"any":     EmptyInterface,
+		"object":  NestedStruct,
+		"Date":    TimeStruct,
+		"number":  goNumbers,
+		"boolean": "bool",
 	},
-	Elm: map[string]*regexp.Regexp{
-		"string": asWord("string"),
-		"Maybe":  asWord(EmptyInterface + "|" + NestedStruct),
-		"Date":   asWord(TimeStruct),
-		"Bool":   asWord("bool"),
-		"Int":    asWord(goInt),
-		"Float":  asWord(goFloat),
+	JSDoc: map[string]string{
+		"*":       EmptyInterface,
+		"Object":  NestedStruct,
+		"Date":    TimeStruct,
+		"number":  goNumbers,
+		"boolean": "bool",
+	},
+	Elm: map[string]string{
+		"string": "string",
+		"Maybe":  EmptyInterface + "|" + NestedStruct,
+		"Date":   TimeStruct,
+		"Bool":   "bool",
+		"Int":    goInt,
+		"Float":  goFloat,
 	},
 }
 
+// convert returns a function converting types for a language.
+func convert(lang Language) func(string) string {
+	return func(s string) string {
+		return updateTypes(lookup(lang).conversions)(s)
+	}
+}
+
 // updateTypes takes a conversion slice and returns
 // a function used as a string replacer
 func updateTypes(replacements map[string]*regexp.Regexp) func(string) string {
@@ -98,3 +108,20 @@ func lineComment(prefix string) func(string) string {
 		return " " + prefix + " " + c
 	}
 }
+
+// jsdocDescription flattens a comment to the single line that follows a JSDoc tag.
+func jsdocDescription(c string) string {
+	if c == "" {
+		return c
+	}
+	return " - " + strings.Join(strings.Fields(c), " ")
+}
+
+// title uppercases the first letter of s.
+func title(s string) string {
+	if s == "" {
+		return s
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
@@ -0,0 +1,76 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// This file contains the logic for loading user template packs from a directory.
+
+// conversionsFile is the name of the file holding a template pack's type conversions.
+const conversionsFile = "conversions.json"
+
+// fragmentExt is the extension of every fragment file in a template pack.
+const fragmentExt = ".tmpl"
+
+// LoadTemplates loads a directory of fragment files and registers them as a new
+// Language called name, or named after the directory when name is empty. Names
+// of registered languages, such as "ts", are an error.
+//
+// Every fragment is a file named after it, such as `header.tmpl` or `fieldName.tmpl`.
+// A missing fragment is empty. A single trailing newline is dropped from every file,
+// so end a file with an empty line to keep one.
+//
+// An optional `conversions.json` holds the Spec's Conversions, mapping each target
+// type to a regular expression of the Go types it replaces, e.g. {"number": "int|int64|float64"}.
+func LoadTemplates(name, dir string) (Language, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	spec := Spec{}
+	fragments := spec.Fragments.byName()
+
+	for _, f := range files {
+		file := f.Name()
+		if f.IsDir() {
+			continue
+		}
+		bs, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return 0, err
+		}
+
+		if file == conversionsFile {
+			if err := json.Unmarshal(bs, &spec.Conversions); err != nil {
+				return 0, fmt.Errorf("%s: %v", file, err)
+			}
+			continue
+		}
+
+		if filepath.Ext(file) != fragmentExt {
+			continue
+		}
+		frag, ok := fragments[strings.TrimSuffix(file, fragmentExt)]
+		if !ok {
+			return 0, fmt.Errorf("%s: unknown template fragment", file)
+		}
+		*frag = strings.TrimSuffix(string(bs), "\n")
+	}
+
+	if name == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return 0, err
+		}
+		name = filepath.Base(abs)
+	}
+	if _, ok := LookupLanguage(name); ok {
+		return 0, fmt.Errorf("language %q is already registered, pick another name for the template pack", name)
+	}
+	return RegisterLanguage(name, spec)
+}
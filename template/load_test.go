@@ -0,0 +1,82 @@
+package template
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LoadTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestLoadTestSuite(t *testing.T) {
+	suite.Run(t, new(LoadTestSuite))
+}
+
+func (s *LoadTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "typewriter")
+	s.Require().NoError(err)
+	s.dir = dir
+}
+
+func (s *LoadTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *LoadTestSuite) write(name, content string) {
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(s.dir, name), []byte(content), 0644))
+}
+
+func (s *LoadTestSuite) TestLoadTemplates() {
+	s.write("declaration.tmpl", "\n{{.Name}} ::=\n")
+	s.write("structOpen.tmpl", " record\n\n")
+	s.write("fieldName.tmpl", "  {{.Name}} is \n")
+	s.write("fieldClose.tmpl", "\n\n")
+	s.write("structClose.tmpl", "end\n")
+	s.write("basic.tmpl", "{{if .Pointer}}maybe {{end}}{{updateType .Type}}\n")
+	s.write("conversions.json", `{"integer": "int|int64"}`)
+
+	lang, err := LoadTemplates("", s.dir)
+	s.Require().NoError(err)
+
+	p := &PackageType{
+		Name: "User",
+		Type: &Struct{Fields: []Field{
+			{Name: "Age", Type: &Basic{"int64", true}, Tag: `json:"age"`},
+		}},
+	}
+	buf := new(bytes.Buffer)
+	s.Require().NoError(p.Template(buf, lang, Options{}))
+	s.Equal(`
+User ::= record
+  age is maybe integer
+end`, buf.String())
+}
+
+func (s *LoadTestSuite) TestLoadTemplatesUnknownFragment() {
+	s.write("structBody.tmpl", "")
+	_, err := LoadTemplates("", s.dir)
+	s.Error(err)
+}
+
+func (s *LoadTestSuite) TestLoadTemplatesInvalidFragment() {
+	s.write("basic.tmpl", "{{.Type")
+	_, err := LoadTemplates("", s.dir)
+	s.Error(err)
+}
+
+func (s *LoadTestSuite) TestLoadTemplatesNamed() {
+	s.write("basic.tmpl", "{{.Type}}")
+	_, err := LoadTemplates("ts", s.dir)
+	s.EqualError(err, `language "ts" is already registered, pick another name for the template pack`)
+
+	lang, err := LoadTemplates("named-pack", s.dir)
+	s.Require().NoError(err)
+	s.Equal("named-pack", lang.String())
+}
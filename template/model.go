@@ -0,0 +1,100 @@
+package template
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/natdm/typewriter/ir"
+)
+
+// This file contains the conversion of the ir model to the types templates are drawn with.
+// Every draw converts the model anew, so drawing never changes it.
+
+// packageType returns the PackageType a declaration is drawn with. Types
+// named in types are drawn as the type they map to.
+func packageType(d *ir.Decl, types map[string]string) *PackageType {
+	p := &PackageType{
+		Name:    d.Name,
+		Comment: d.Doc,
+	}
+	for _, v := range d.TypeParams {
+		p.TypeParams = append(p.TypeParams, v.Name)
+	}
+	if d.Type == nil {
+		return p
+	}
+
+	switch d.Type.Kind {
+	case ir.Struct:
+		s := &Struct{
+			Strict:  d.Strict,
+			Inexact: d.Inexact,
+		}
+		for _, v := range d.Type.Embedded {
+			if to, ok := types[v]; ok {
+				v = to
+			}
+			s.Embedded = append(s.Embedded, v)
+		}
+		for _, v := range d.Type.Fields {
+			s.Fields = append(s.Fields, Field{
+				Name:        v.Name,
+				Type:        typeSpec(v.Type, types),
+				DocComment:  v.Doc,
+				LineComment: v.Comment,
+				Tag:         v.Tag,
+			})
+		}
+		p.Type = s
+	case ir.Union:
+		p.Type = union(d.Name, d.Type, types)
+	default:
+		p.Type = typeSpec(d.Type, types)
+	}
+	return p
+}
+
+// typeSpec returns the TypeSpec a type is drawn with. Structs below
+// the package level are drawn as nested structs.
+func typeSpec(t *ir.Type, types map[string]string) TypeSpec {
+	switch t.Kind {
+	case ir.Array:
+		return &Array{Type: typeSpec(t.Elem, types)}
+	case ir.Map:
+		return &Map{Key: typeSpec(t.Key, types), Value: typeSpec(t.Elem, types)}
+	case ir.Struct:
+		return &Basic{Type: NestedStruct, Pointer: t.Pointer}
+	case ir.Enum:
+		e := &Enum{Type: t.Name}
+		for _, v := range t.Values {
+			e.Values = append(e.Values, v.Value)
+		}
+		return e
+	case ir.Union:
+		return union("", t, types)
+	}
+	if to, ok := types[t.Name]; ok {
+		return &Basic{Type: to, Pointer: t.Pointer}
+	}
+	if len(t.Args) > 0 {
+		g := &Generic{Type: t.Name, Pointer: t.Pointer}
+		for _, v := range t.Args {
+			g.Args = append(g.Args, typeSpec(v, types))
+		}
+		return g
+	}
+	return &Basic{Type: t.Name, Pointer: t.Pointer}
+}
+
+// union returns the Union an interface named name is drawn as.
+func union(name string, t *ir.Type, types map[string]string) *Union {
+	u := &Union{Name: name, Discriminator: t.Discriminator}
+	for _, v := range t.Variants {
+		u.Variants = append(u.Variants, Variant{
+			Type: typeSpec(v.Type, types),
+			Name: v.Type.Name[strings.LastIndex(v.Type.Name, ".")+1:],
+			Tag:  strconv.Quote(v.Tag),
+		})
+	}
+	return u
+}
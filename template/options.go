@@ -0,0 +1,152 @@
+package template
+
+import (
+	"strings"
+	"unicode"
+)
+
+// This file contains the per-run options that change how a language is drawn.
+
+// Options are handed to every template, where they are available through the
+// `opts` template function. The zero value draws every language's default output.
+type Options struct {
+	TS   TSOptions
+	Flow FlowOptions
+
+	// Types map Go types to the type drawn in their place, as if every field
+	// of that type had a `tw` tag, e.g. {"uuid.UUID": "string"}. Mapped types
+	// still go through the language's conversions.
+	Types map[string]string
+
+	// Naming names the fields without a name in their json tag.
+	Naming Naming
+
+	// EnumUnions draws types with declared constants as a union of their
+	// values, such as "red" | "green", in languages with an Enum fragment.
+	// Otherwise they are drawn as their underlying type.
+	EnumUnions bool
+}
+
+// TSOptions are options for Typescript output.
+type TSOptions struct {
+	// Export exports every declaration so other modules can import it.
+	Export bool
+
+	// Interface declares structs as interfaces rather than type aliases.
+	// Interfaces extend their embedded types instead of intersecting them.
+	Interface bool
+
+	// Extends declares structs with embedded types as interfaces extending
+	// those types, even when Interface is not set.
+	Extends bool
+
+	// Readonly marks every property as readonly.
+	Readonly bool
+
+	// Namespace wraps every declaration in a `declare namespace` block so the
+	// output can be used as a global .d.ts file.
+	Namespace string
+
+	// Module wraps every declaration in a `declare module "..."` block.
+	// Only one of Namespace and Module may be set.
+	Module string
+}
+
+// Ambient reports whether declarations are wrapped in an ambient block.
+func (o TSOptions) Ambient() bool {
+	return o.Namespace != "" || o.Module != ""
+}
+
+// DeclaresInterface reports whether t is declared as an interface.
+func (o TSOptions) DeclaresInterface(t Templater) bool {
+	s, ok := t.(*Struct)
+	if !ok {
+		return false
+	}
+	return o.Interface || (o.Extends && len(s.Embedded) > 0)
+}
+
+// FlowOptions are options for Flow output.
+type FlowOptions struct {
+	// Exact makes every object exact, not only the ones flagged with @strict.
+	// Objects flagged with @inexact are written with an explicit `...`.
+	Exact bool
+
+	// ReadOnly wraps every object in $ReadOnly<>.
+	ReadOnly bool
+
+	// Covariant marks every property as read-only with a `+` variance marker.
+	Covariant bool
+}
+
+// IsExact reports whether s is written as an exact object.
+func (o FlowOptions) IsExact(s *Struct) bool {
+	return !s.Inexact && (s.Strict || o.Exact)
+}
+
+// Naming is a strategy for naming fields without a name in their json tag.
+type Naming string
+
+// naming strategies
+const (
+	// NamingGo keeps the Go field name, like encoding/json does.
+	NamingGo Naming = ""
+
+	// NamingCamel lowercases the first word, e.g. UserID becomes userID.
+	NamingCamel Naming = "camel"
+
+	// NamingSnake lowercases every word and joins them with underscores,
+	// e.g. UserID becomes user_id.
+	NamingSnake Naming = "snake"
+)
+
+// Valid reports whether n is a known strategy.
+func (n Naming) Valid() bool {
+	switch n {
+	case NamingGo, NamingCamel, NamingSnake:
+		return true
+	}
+	return false
+}
+
+// Apply names a Go field.
+func (n Naming) Apply(name string) string {
+	switch n {
+	case NamingCamel:
+		words := splitWords(name)
+		if len(words) == 0 {
+			return name
+		}
+		return strings.ToLower(words[0]) + strings.Join(words[1:], "")
+	case NamingSnake:
+		return strings.ToLower(strings.Join(splitWords(name), "_"))
+	}
+	return name
+}
+
+// splitWords splits a Go name on case changes, keeping initialisms together,
+// e.g. HTTPServerID becomes HTTP, Server, ID.
+func splitWords(name string) []string {
+	rs := []rune(name)
+	var words []string
+	start := 0
+	for i := 1; i < len(rs); i++ {
+		switch {
+		case rs[i] == '_':
+			if start < i {
+				words = append(words, string(rs[start:i]))
+			}
+			start = i + 1
+		case unicode.IsUpper(rs[i]) && !unicode.IsUpper(rs[i-1]) && rs[i-1] != '_':
+			words = append(words, string(rs[start:i]))
+			start = i
+		case unicode.IsUpper(rs[i-1]) && unicode.IsUpper(rs[i]) && i+1 < len(rs) && unicode.IsLower(rs[i+1]):
+			words = append(words, string(rs[start:i]))
+			start = i
+		}
+	}
+	if start < len(rs) {
+		words = append(words, string(rs[start:]))
+	}
+	return words
+}
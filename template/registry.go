@@ -0,0 +1,184 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"text/template"
+)
+
+// This file contains the registry of every language types can be drawn in.
+
+// Language is a registered language
+type Language int
+
+// built-in languages, registered in this order
+const (
+	Typescript Language = iota
+	Flow
+	Elm
+	JSDoc
+)
+
+// Spec describes how a Language is drawn.
+type Spec struct {
+	// Fragments are the templates every type is drawn with.
+	Fragments Fragments
+
+	// Conversions map each target type to a regular expression of the Go types
+	// it replaces, e.g. {"number": "int|int64|float64"}. Go types are matched as
+	// whole words. Converted types are available to the fragments through the
+	// `updateType` template function.
+	Conversions map[string]string
+
+	// Funcs are template functions only available to this language's fragments.
+	Funcs template.FuncMap
+
+	// ExpandEmbedded is set for languages without intersection types,
+	// which need embedded structs expanded into their fields.
+	ExpandEmbedded bool
+}
+
+// language is a registered Spec, ready to draw with
+type language struct {
+	name        string
+	spec        Spec
+	conversions map[string]*regexp.Regexp
+	funcs       template.FuncMap
+}
+
+var registry = struct {
+	sync.RWMutex
+	languages []*language
+	names     map[string]Language
+}{names: make(map[string]Language)}
+
+func init() {
+	builtins := []struct {
+		name      string
+		lang      Language
+		fragments Fragments
+		expand    bool
+	}{
+		{"ts", Typescript, tsTemplates, false},
+		{"flow", Flow, flowTemplates, false},
+		{"elm", Elm, elmTemplates, true},
+		{"jsdoc", JSDoc, jsdocTemplates, true},
+	}
+	for _, b := range builtins {
+		lang, err := RegisterLanguage(b.name, Spec{
+			Fragments:      b.fragments,
+			Conversions:    conversions[b.lang],
+			ExpandEmbedded: b.expand,
+		})
+		if err != nil {
+			panic(err)
+		}
+		if lang != b.lang {
+			panic(fmt.Sprintf("built-in language %s registered as %d", b.name, lang))
+		}
+	}
+}
+
+// RegisterLanguage registers a Spec under a name, so types can be drawn with it.
+// Every fragment is parsed up front, so a broken template is reported here
+// instead of while drawing.
+func RegisterLanguage(name string, spec Spec) (Language, error) {
+	if name == "" {
+		return 0, fmt.Errorf("language name is empty")
+	}
+
+	l := &language{
+		name:        name,
+		spec:        spec,
+		conversions: make(map[string]*regexp.Regexp),
+		funcs:       template.FuncMap{},
+	}
+	for target, goTypes := range spec.Conversions {
+		r, err := regexp.Compile("\\b(" + goTypes + ")\\b")
+		if err != nil {
+			return 0, fmt.Errorf("%s: conversion to %q: %v", name, target, err)
+		}
+		l.conversions[target] = r
+	}
+	l.funcs["updateType"] = updateTypes(l.conversions)
+	for k, v := range spec.Funcs {
+		l.funcs[k] = v
+	}
+
+	for frag, tpl := range spec.Fragments.byName() {
+		if _, err := template.New(frag).
+			Funcs(funcMap).
+			Funcs(l.funcs).
+			Funcs(template.FuncMap{"opts": func() Options { return Options{} }}).
+			Parse(*tpl); err != nil {
+			return 0, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.names[name]; ok {
+		return 0, fmt.Errorf("language %q is already registered", name)
+	}
+	lang := Language(len(registry.languages))
+	registry.languages = append(registry.languages, l)
+	registry.names[name] = lang
+	return lang, nil
+}
+
+// LookupLanguage returns the language registered under a name.
+func LookupLanguage(name string) (Language, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	lang, ok := registry.names[name]
+	return lang, ok
+}
+
+// Languages returns the names of every registered language, sorted.
+func Languages() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.names))
+	for name := range registry.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String returns the name the language is registered under.
+func (i Language) String() string {
+	if !i.registered() {
+		return fmt.Sprintf("Language(%d)", i)
+	}
+	return lookup(i).name
+}
+
+// Spec returns the Spec the language was registered with.
+func (i Language) Spec() Spec {
+	return lookup(i).spec
+}
+
+func (i Language) registered() bool {
+	registry.RLock()
+	defer registry.RUnlock()
+	return i >= 0 && int(i) < len(registry.languages)
+}
+
+// lookup returns a registered language. Unregistered languages have no
+// fragments, so they draw nothing.
+func lookup(lang Language) *language {
+	registry.RLock()
+	defer registry.RUnlock()
+	if lang < 0 || int(lang) >= len(registry.languages) {
+		return &language{}
+	}
+	return registry.languages[lang]
+}
+
+// fragments returns the fragments of a language
+func fragments(lang Language) Fragments {
+	return lookup(lang).spec.Fragments
+}
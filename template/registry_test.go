@@ -0,0 +1,70 @@
+package template
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+type RegistryTestSuite struct {
+	suite.Suite
+}
+
+func TestRegistryTestSuite(t *testing.T) {
+	suite.Run(t, new(RegistryTestSuite))
+}
+
+func (s *RegistryTestSuite) TestBuiltins() {
+	for name, lang := range map[string]Language{"ts": Typescript, "flow": Flow, "elm": Elm, "jsdoc": JSDoc} {
+		l, ok := LookupLanguage(name)
+		s.True(ok, name)
+		s.Equal(lang, l)
+		s.Equal(name, l.String())
+	}
+	s.True(Elm.Spec().ExpandEmbedded)
+	s.False(Flow.Spec().ExpandEmbedded)
+	s.Equal("Language(-1)", Language(-1).String())
+}
+
+func (s *RegistryTestSuite) TestRegisterLanguage() {
+	lang, err := RegisterLanguage("registry-test", Spec{
+		Fragments: Fragments{
+			Declaration: `{{.Name}} = `,
+			Basic:       `{{shout (updateType .Type)}}`,
+		},
+		Conversions: map[string]string{"integer": "int|int64"},
+		Funcs:       map[string]interface{}{"shout": strings.ToUpper},
+	})
+	s.Require().NoError(err)
+
+	l, ok := LookupLanguage("registry-test")
+	s.True(ok)
+	s.Equal(lang, l)
+
+	buf := new(bytes.Buffer)
+	p := &PackageType{Name: "Id", Type: &Basic{"int64", false}}
+	s.Require().NoError(p.Template(buf, lang, Options{}))
+	s.Equal("Id = INTEGER", buf.String())
+
+	_, err = RegisterLanguage("registry-test", Spec{})
+	s.Error(err)
+}
+
+func (s *RegistryTestSuite) TestRegisterLanguageInvalid() {
+	_, err := RegisterLanguage("registry-test-invalid", Spec{Fragments: Fragments{Basic: `{{.Type`}})
+	s.Error(err)
+
+	_, err = RegisterLanguage("registry-test-conversion", Spec{Conversions: map[string]string{"number": "int("}})
+	s.Error(err)
+
+	_, ok := LookupLanguage("registry-test-invalid")
+	s.False(ok)
+}
+
+func (s *RegistryTestSuite) TestDrawUnknownLanguage() {
+	_, err := Draw(nil, new(bytes.Buffer), Language(1000), Options{}, log.New())
+	s.Error(err)
+}
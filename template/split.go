@@ -0,0 +1,268 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/natdm/typewriter/ir"
+	log "github.com/sirupsen/logrus"
+)
+
+// This file contains the logic for splitting drawn types into a file per Go package or per type.
+
+// Split is how drawn types are split into files.
+type Split int
+
+// splits
+const (
+	// SplitNone draws every type into a single file.
+	SplitNone Split = iota
+
+	// SplitPackage draws the types of every Go package into a file of their own.
+	SplitPackage
+
+	// SplitType draws every type into a file of its own.
+	SplitType
+)
+
+// defaultUnit names the file of types without a package.
+const defaultUnit = "types"
+
+// ParseSplit returns the Split named "package" or "type". An empty name is SplitNone.
+func ParseSplit(name string) (Split, error) {
+	switch name {
+	case "":
+		return SplitNone, nil
+	case "package":
+		return SplitPackage, nil
+	case "type":
+		return SplitType, nil
+	}
+	return SplitNone, fmt.Errorf("unknown split %q, pick one of 'package' and 'type'", name)
+}
+
+// importSpec is a split file, as seen by the Module, Import and Index fragments.
+type importSpec struct {
+	// Path is the path of the file relative to the importing one, without extension.
+	Path string
+
+	// Module is the name of the file without extension.
+	Module string
+
+	// Names are the names of the types imported from the file, sorted.
+	Names []string
+
+	// Decoders are the Names drawn with a decoder, for languages with a
+	// Decoder fragment.
+	Decoders []string
+}
+
+// DrawFiles draws types into a file per Go package or per type, named by the
+// language's FileName fragment, and writes each to the writer open returns for
+// its name. Every file imports the types it references from other files. With
+// index set, an index file re-exporting every file is drawn too, for languages
+// with an Index fragment.
+//
+// Typescript declarations are always exported, so they can be imported.
+func DrawFiles(t map[string]*ir.Decl, open func(name string) (io.Writer, error), split Split, index bool, lang Language, opts Options, logger log.FieldLogger) (int, error) {
+	if !lang.registered() {
+		return 0, fmt.Errorf("unknown language: %s", lang)
+	}
+	frags := fragments(lang)
+	if frags.FileName == "" {
+		return 0, fmt.Errorf("%s output cannot be split into files", lang)
+	}
+	if split == SplitNone {
+		return 0, fmt.Errorf("no split to draw files by")
+	}
+	if lang == Typescript {
+		if opts.TS.Ambient() {
+			return 0, fmt.Errorf("ambient Typescript declarations cannot be split into files")
+		}
+		opts.TS.Export = true
+	}
+
+	units := make(map[string]string)
+	byUnit := make(map[string][]string)
+	for name, d := range t {
+		u := d.Name
+		if split == SplitPackage {
+			u = d.Package
+		}
+		if u == "" {
+			u = defaultUnit
+		}
+		units[name] = u
+		byUnit[u] = append(byUnit[u], name)
+	}
+
+	files := make(map[string]string)
+	unitsByFile := make(map[string]string)
+	order := make([]string, 0, len(byUnit))
+	for u, names := range byUnit {
+		sort.Strings(names)
+		name, err := execute(lang, frags.FileName, opts, u)
+		if err != nil {
+			return 0, err
+		}
+		if other, ok := unitsByFile[name]; ok {
+			return 0, fmt.Errorf("%s and %s are both drawn to %s", other, u, name)
+		}
+		files[u], unitsByFile[name] = name, u
+		order = append(order, u)
+	}
+	sort.Strings(order)
+
+	decoders := decoded(t, lang)
+	for _, u := range order {
+		w, err := open(files[u])
+		if err != nil {
+			return 0, err
+		}
+		if err := drawFile(t, u, byUnit, units, files, decoders, w, lang, opts, logger); err != nil {
+			return 0, fmt.Errorf("%s: %v", files[u], err)
+		}
+		logger.WithField("file", files[u]).Debug("created file")
+	}
+
+	if index && frags.Index != "" {
+		name, err := execute(lang, frags.FileName, opts, "index")
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := unitsByFile[name]; ok {
+			return 0, fmt.Errorf("index and %s are both drawn to %s", unitsByFile[name], name)
+		}
+		w, err := open(name)
+		if err != nil {
+			return 0, err
+		}
+		if err := Header(w, lang, opts); err != nil {
+			return 0, err
+		}
+		for _, u := range order {
+			if err := newTemplate(lang, frags.Index, opts).Execute(w, spec(files[u], byUnit[u])); err != nil {
+				return 0, err
+			}
+		}
+		if err := Footer(w, lang, opts); err != nil {
+			return 0, err
+		}
+	}
+	return len(t), nil
+}
+
+// FileExt returns the extension of the files a language is split into, such as
+// ".ts", empty for languages that cannot be split.
+func FileExt(lang Language, opts Options) (string, error) {
+	if !lang.registered() {
+		return "", fmt.Errorf("unknown language: %s", lang)
+	}
+	frags := fragments(lang)
+	if frags.FileName == "" {
+		return "", nil
+	}
+	name, err := execute(lang, frags.FileName, opts, defaultUnit)
+	if err != nil {
+		return "", err
+	}
+	return path.Ext(name), nil
+}
+
+// drawFile draws the types of a unit, after importing the types they reference from other units.
+func drawFile(t map[string]*ir.Decl, u string, byUnit map[string][]string, units, files map[string]string, decoders map[string]bool, w io.Writer, lang Language, opts Options, logger log.FieldLogger) error {
+	// References to types in other packages are drawn by their bare,
+	// imported name instead of the package qualified one.
+	types := make(map[string]string)
+	for k, v := range opts.Types {
+		types[k] = v
+	}
+	imports := make(map[string]map[string]bool)
+	for _, k := range byUnit[u] {
+		for _, ref := range t[k].References() {
+			if _, ok := opts.Types[ref]; ok {
+				continue
+			}
+			name, ok := ir.Resolve(t, ref)
+			if !ok {
+				continue
+			}
+			if name != ref {
+				types[ref] = name
+			}
+			if units[name] == u {
+				continue
+			}
+			if imports[units[name]] == nil {
+				imports[units[name]] = make(map[string]bool)
+			}
+			imports[units[name]][name] = true
+		}
+	}
+	opts.Types = types
+
+	if err := Header(w, lang, opts); err != nil {
+		return err
+	}
+	frags := fragments(lang)
+	if frags.Module != "" {
+		if err := newTemplate(lang, frags.Module, opts).Execute(w, spec(files[u], byUnit[u])); err != nil {
+			return err
+		}
+	}
+
+	for _, k := range byUnit[u] {
+		if decoders[k] {
+			if err := newTemplate(lang, frags.DecoderHeader, opts).Execute(w, nil); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	imported := make([]string, 0, len(imports))
+	for v := range imports {
+		imported = append(imported, v)
+	}
+	sort.Strings(imported)
+	for _, v := range imported {
+		names := make([]string, 0, len(imports[v]))
+		for name := range imports[v] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		s := spec(files[v], names)
+		for _, name := range names {
+			if decoders[name] {
+				s.Decoders = append(s.Decoders, name)
+			}
+		}
+		if err := newTemplate(lang, frags.Import, opts).Execute(w, s); err != nil {
+			return err
+		}
+	}
+
+	if err := drawTypes(t, byUnit[u], decoders, w, lang, opts, logger); err != nil {
+		return err
+	}
+	return Footer(w, lang, opts)
+}
+
+// spec returns the importSpec of a file.
+func spec(file string, names []string) importSpec {
+	module := strings.TrimSuffix(file, path.Ext(file))
+	return importSpec{Path: "./" + module, Module: module, Names: names}
+}
+
+// execute draws a fragment to a string.
+func execute(lang Language, tpl string, opts Options, data interface{}) (string, error) {
+	buf := bytes.Buffer{}
+	if err := newTemplate(lang, tpl, opts).Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
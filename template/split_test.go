@@ -0,0 +1,107 @@
+package template
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/natdm/typewriter/ir"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+type SplitTestSuite struct {
+	suite.Suite
+}
+
+func TestSplitTestSuite(t *testing.T) {
+	suite.Run(t, new(SplitTestSuite))
+}
+
+var splitTypes = map[string]*ir.Decl{
+	"User": {Name: "User", Package: "models", Type: &ir.Type{Kind: ir.Struct, Fields: []*ir.Field{
+		{Name: "Role", Type: irBasic("auth.Role", false), Tag: `json:"role"`},
+		{Name: "Friends", Type: &ir.Type{Kind: ir.Array, Elem: irBasic("User", false)}, Tag: `json:"friends"`},
+		{Name: "Perms", Type: &ir.Type{Kind: ir.Map, Key: irBasic("string", false), Elem: irBasic("Permission", false)}, Tag: `json:"perms"`},
+	}}},
+	"Role":       {Name: "Role", Package: "auth", Type: irBasic("string", false)},
+	"Permission": {Name: "Permission", Package: "auth", Type: irBasic("int", false)},
+}
+
+func (s *SplitTestSuite) draw(split Split, lang Language, opts Options) map[string]string {
+	bufs := make(map[string]*bytes.Buffer)
+	ct, err := DrawFiles(splitTypes, func(name string) (io.Writer, error) {
+		s.NotContains(bufs, name, "every file is opened once")
+		bufs[name] = new(bytes.Buffer)
+		return bufs[name], nil
+	}, split, true, lang, opts, log.New())
+	s.Require().NoError(err)
+	s.Equal(3, ct)
+
+	files := make(map[string]string)
+	for k, v := range bufs {
+		files[k] = v.String()
+	}
+	return files
+}
+
+func (s *SplitTestSuite) TestByPackage() {
+	files := s.draw(SplitPackage, Typescript, Options{})
+	s.Len(files, 3)
+	s.Contains(files["models.ts"], "import type { Permission, Role } from \"./auth\"\n")
+	s.Contains(files["models.ts"], "export type User = {\n\trole: Role,\n")
+	s.NotContains(files["models.ts"], "import type { User }")
+	s.Contains(files["auth.ts"], "export type Role = string\n")
+	s.NotContains(files["auth.ts"], "import")
+	s.Contains(files["index.ts"], "export * from \"./auth\"\nexport * from \"./models\"\n")
+
+	files = s.draw(SplitPackage, Flow, Options{})
+	s.Contains(files["models.js"], "import type { Permission, Role } from './auth'\n")
+	s.Contains(files["index.js"], "export type { Permission, Role } from './auth'\n")
+
+	files = s.draw(SplitPackage, Elm, Options{})
+	s.Len(files, 2, "elm has no index")
+	s.Contains(files["Models.elm"], "module Models exposing (..)\n\nimport Auth exposing (Permission, Role)\n")
+}
+
+func (s *SplitTestSuite) TestByType() {
+	files := s.draw(SplitType, Flow, Options{})
+	s.Len(files, 4)
+	s.Contains(files["User.js"], "import type { Permission } from './Permission'\nimport type { Role } from './Role'\n")
+	s.Contains(files["Role.js"], "export type Role = string\n")
+}
+
+func (s *SplitTestSuite) TestElmDecoders() {
+	types := map[string]*ir.Decl{
+		"Shape": {Name: "Shape", Package: "shapes", Type: &ir.Type{Kind: ir.Union, Variants: []*ir.Variant{{Type: irBasic("geo.Circle", false), Tag: "Circle"}}}},
+		"Circle": {Name: "Circle", Package: "geo", Type: &ir.Type{Kind: ir.Struct, Fields: []*ir.Field{
+			{Name: "Radius", Type: irBasic("float64", false), Tag: `json:"radius"`},
+		}}},
+		"Point": {Name: "Point", Package: "geo", Type: &ir.Type{Kind: ir.Struct}},
+	}
+	bufs := make(map[string]*bytes.Buffer)
+	_, err := DrawFiles(types, func(name string) (io.Writer, error) {
+		bufs[name] = new(bytes.Buffer)
+		return bufs[name], nil
+	}, SplitPackage, false, Elm, Options{}, log.New())
+	s.Require().NoError(err)
+
+	s.Contains(bufs["Shapes.elm"].String(), "module Shapes exposing (..)\n\nimport Json.Decode\nimport Geo exposing (Circle, decodeCircle)\n")
+	s.Contains(bufs["Shapes.elm"].String(), "\n    = ShapeCircle Circle\n")
+	s.Contains(bufs["Geo.elm"].String(), "\ndecodeCircle : Json.Decode.Decoder Circle\n")
+	s.NotContains(bufs["Geo.elm"].String(), "decodePoint", "only the types of unions are decoded")
+}
+
+func (s *SplitTestSuite) TestErrors() {
+	open := func(string) (io.Writer, error) { return new(bytes.Buffer), nil }
+	_, err := DrawFiles(splitTypes, open, SplitNone, false, Flow, Options{}, log.New())
+	s.Error(err)
+	_, err = DrawFiles(splitTypes, open, SplitPackage, false, Typescript, Options{TS: TSOptions{Namespace: "Api"}}, log.New())
+	s.Error(err)
+
+	_, err = ParseSplit("file")
+	s.Error(err)
+	split, err := ParseSplit("type")
+	s.NoError(err)
+	s.Equal(SplitType, split)
+}
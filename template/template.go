@@ -17,7 +17,7 @@ import (
 
 // Templater interface is able to write a template to a writer, based on a Language
 type Templater interface {
-	Template(w io.Writer, lang Language) error
+	Template(w io.Writer, lang Language, opts Options) error
 }
 
 type TypeSpec interface {
@@ -28,8 +28,13 @@ type TypeSpec interface {
 var errNoType = errors.New("type not stored in package level type declaration")
 
 // Header is the file header
-func Header(w io.Writer, lang Language) error {
-	return newTemplate(templates[lang].header).Execute(w, nil)
+func Header(w io.Writer, lang Language, opts Options) error {
+	return newTemplate(lang, fragments(lang).Header, opts).Execute(w, nil)
+}
+
+// Footer is the file footer
+func Footer(w io.Writer, lang Language, opts Options) error {
+	return newTemplate(lang, fragments(lang).Footer, opts).Execute(w, nil)
 }
 
 // Raw is a template with raw input in it
@@ -48,21 +53,32 @@ type TimeType struct {
 	Tag     string
 }
 
-func (t *TimeType) Template(w io.Writer, lang Language) error {
-	return newTemplate(templates[lang].timeType).Execute(w, t)
+func (t *TimeType) Template(w io.Writer, lang Language, opts Options) error {
+	return newTemplate(lang, fragments(lang).TimeType, opts).Execute(w, t)
 }
 
 // PackageType is a package-level type. Any package type will
 // be templated with a full type creation statement and possibly a comment
 type PackageType struct {
-	Name    string
-	Comment string
-	Type    Templater
-	Tag     string
+	Name       string
+	Comment    string
+	Type       Templater
+	Tag        string
+	TypeParams []string
+}
+
+// IsUnion reports whether the type is a Union, for languages declaring unions
+// differently.
+func (t *PackageType) IsUnion() bool {
+	_, ok := t.Type.(*Union)
+	return ok
 }
 
-func (t *PackageType) Template(w io.Writer, lang Language) error {
-	if err := newTemplate(templates[lang].declaration).Execute(w, t); err != nil {
+func (t *PackageType) Template(w io.Writer, lang Language, opts Options) error {
+	if fragments(lang).Typedef != "" {
+		return t.typedef(w, lang, opts)
+	}
+	if err := newTemplate(lang, fragments(lang).Declaration, opts).Execute(w, t); err != nil {
 		return err
 	}
 	if t.Type == nil {
@@ -70,7 +86,25 @@ func (t *PackageType) Template(w io.Writer, lang Language) error {
 		return errNoType
 	}
 
-	return t.Type.Template(w, lang)
+	return t.Type.Template(w, lang, opts)
+}
+
+// typedef renders the type first so the typedef fragment can place it before the name.
+func (t *PackageType) typedef(w io.Writer, lang Language, opts Options) error {
+	if t.Type == nil {
+		log.WithError(errNoType).WithField("name", t.Name).Error("error while writing package type")
+		return errNoType
+	}
+	buf := bytes.Buffer{}
+	if err := t.Type.Template(&buf, lang, opts); err != nil {
+		return err
+	}
+	_, isStruct := t.Type.(*Struct)
+	return newTemplate(lang, fragments(lang).Typedef, opts).Execute(w, struct {
+		*PackageType
+		Body     string
+		IsStruct bool
+	}{t, buf.String(), isStruct})
 }
 
 // Basic is a basic type. Ints, strings, bools, etc.. or a custom type.
@@ -79,34 +113,145 @@ type Basic struct {
 	Pointer bool
 }
 
-func (t *Basic) Template(w io.Writer, lang Language) error {
-	return newTemplate(templates[lang].basic).Execute(w, t)
+// basic is a Basic as drawn, with the type arguments of a Generic.
+type basic struct {
+	*Basic
+	TypeArgs []string
+}
+
+func (t *Basic) Template(w io.Writer, lang Language, opts Options) error {
+	return newTemplate(lang, fragments(lang).Basic, opts).Execute(w, basic{Basic: t})
 }
 
 func (t *Basic) IsPointer() bool {
 	return t.Pointer
 }
 
+// Generic is an instantiated generic type, such as Page[User].
+type Generic struct {
+	Type    string
+	Pointer bool
+	Args    []TypeSpec
+}
+
+func (t *Generic) Template(w io.Writer, lang Language, opts Options) error {
+	b := basic{Basic: &Basic{Type: t.Type, Pointer: t.Pointer}}
+	for _, v := range t.Args {
+		buf := bytes.Buffer{}
+		if err := v.Template(&buf, lang, opts); err != nil {
+			return err
+		}
+		b.TypeArgs = append(b.TypeArgs, strings.TrimSpace(buf.String()))
+	}
+	return newTemplate(lang, fragments(lang).Basic, opts).Execute(w, b)
+}
+
+func (t *Generic) IsPointer() bool {
+	return t.Pointer
+}
+
+// Enum is a basic type limited to a set of values.
+type Enum struct {
+	Type string
+
+	// Values are the values as encoded in JSON.
+	Values []string
+}
+
+// Template draws the values of the enum with Options.EnumUnions, or else the
+// basic type, as for languages without an Enum fragment.
+func (t *Enum) Template(w io.Writer, lang Language, opts Options) error {
+	if !opts.EnumUnions || fragments(lang).Enum == "" {
+		return (&Basic{Type: t.Type}).Template(w, lang, opts)
+	}
+	return newTemplate(lang, fragments(lang).Enum, opts).Execute(w, t)
+}
+
+func (t *Enum) IsPointer() bool {
+	return false
+}
+
+// Union is one of the types implementing an interface, its Variants.
+type Union struct {
+	// Name is the name of the interface.
+	Name string
+
+	// Discriminator is the JSON field the variants are told apart by. Without
+	// it, variants are told apart by their shape.
+	Discriminator string
+
+	Variants []Variant
+}
+
+// Variant is a type implementing a Union.
+type Variant struct {
+	Type TypeSpec
+
+	// Name is the bare name of the type, without its package.
+	Name string
+
+	// Tag is the value of the Discriminator field as encoded in JSON, e.g. `"circle"`.
+	Tag string
+}
+
+// variant is a Variant as drawn, with its type rendered as .Body.
+type variant struct {
+	Variant
+	Body string
+}
+
+// Template draws the variants of the union, or the empty interface for
+// languages without a Union fragment.
+func (t *Union) Template(w io.Writer, lang Language, opts Options) error {
+	if fragments(lang).Union == "" {
+		return (&Basic{Type: EmptyInterface, Pointer: true}).Template(w, lang, opts)
+	}
+	u := struct {
+		*Union
+		Key      string
+		Variants []variant
+	}{Union: t, Key: t.Discriminator}
+	switch lang {
+	case Typescript, Flow:
+		if propertyShouldBeQuoted(u.Key) {
+			u.Key = fmt.Sprintf(`"%s"`, u.Key)
+		}
+	default:
+	}
+	for _, v := range t.Variants {
+		buf := bytes.Buffer{}
+		if err := v.Type.Template(&buf, lang, opts); err != nil {
+			return err
+		}
+		u.Variants = append(u.Variants, variant{Variant: v, Body: strings.TrimSpace(buf.String())})
+	}
+	return newTemplate(lang, fragments(lang).Union, opts).Execute(w, u)
+}
+
+func (t *Union) IsPointer() bool {
+	return false
+}
+
 type Map struct {
 	Key   Templater
 	Value Templater
 }
 
-func (t *Map) Template(w io.Writer, lang Language) error {
-	if err := newTemplate(templates[lang].mapKey).Execute(w, t); err != nil {
+func (t *Map) Template(w io.Writer, lang Language, opts Options) error {
+	if err := newTemplate(lang, fragments(lang).MapKey, opts).Execute(w, t); err != nil {
 		return err
 	}
-	if err := t.Key.Template(w, lang); err != nil {
+	if err := t.Key.Template(w, lang, opts); err != nil {
 		return err
 	}
-	if err := newTemplate(templates[lang].mapValue).Execute(w, t); err != nil {
+	if err := newTemplate(lang, fragments(lang).MapValue, opts).Execute(w, t); err != nil {
 		return err
 	}
 
-	if err := t.Value.Template(w, lang); err != nil {
+	if err := t.Value.Template(w, lang, opts); err != nil {
 		return err
 	}
-	return newTemplate(templates[lang].mapClose).Execute(w, t)
+	return newTemplate(lang, fragments(lang).MapClose, opts).Execute(w, t)
 }
 
 func (t *Map) IsPointer() bool {
@@ -120,28 +265,28 @@ type Array struct {
 
 var simpleType = regexp.MustCompile("^[a-zA-Z0-9_.]+$")
 
-func (t *Array) Template(w io.Writer, lang Language) error {
+func (t *Array) Template(w io.Writer, lang Language, opts Options) error {
 	buf := bytes.Buffer{}
-	if err := t.Type.Template(&buf, lang); err != nil {
+	if err := t.Type.Template(&buf, lang, opts); err != nil {
 		return err
 	}
 	elemTypeAsBytes := buf.Bytes()
 
-	open := templates[lang].arrayOpen
-	close := templates[lang].arrayClose
+	open := fragments(lang).ArrayOpen
+	close := fragments(lang).ArrayClose
 
 	if simpleType.Find(elemTypeAsBytes) != nil {
-		open = templates[lang].arrayShortOpen
-		close = templates[lang].arrayShortClose
+		open = fragments(lang).ArrayShortOpen
+		close = fragments(lang).ArrayShortClose
 	}
 
-	if err := newTemplate(open).Execute(w, t); err != nil {
+	if err := newTemplate(lang, open, opts).Execute(w, t); err != nil {
 		return err
 	}
 	if _, err := w.Write(elemTypeAsBytes); err != nil {
 		return err
 	}
-	return newTemplate(close).Execute(w, t)
+	return newTemplate(lang, close, opts).Execute(w, t)
 }
 
 func (t *Array) IsPointer() bool {
@@ -155,39 +300,42 @@ type Struct struct {
 	// Strict is just for Flow types.
 	Strict bool
 
+	// Inexact is just for Flow types.
+	Inexact bool
+
 	// Embedded are the embedded types for a struct
 	Embedded []string
 }
 
-func (t *Struct) Template(w io.Writer, lang Language) error {
-	if err := newTemplate(templates[lang].structOpen).Execute(w, t); err != nil {
+func (t *Struct) Template(w io.Writer, lang Language, opts Options) error {
+	if err := newTemplate(lang, fragments(lang).StructOpen, opts).Execute(w, t); err != nil {
 		return err
 	}
 	for i, v := range t.Fields {
-		if v.DocComment != "" {
+		if v.DocComment != "" && fragments(lang).FieldDocComment != "" {
 			w.Write([]byte{'\n'})
-			if err := newTemplate(templates[lang].fieldDocComment).Execute(w, v); err != nil {
+			if err := newTemplate(lang, fragments(lang).FieldDocComment, opts).Execute(w, v); err != nil {
 				return err
 			}
 		}
-		if err := v.Template(w, lang); err != nil {
+		if err := v.Template(w, lang, opts); err != nil {
 			return err
 		}
 		if i < len(t.Fields)-1 {
-			if err := newTemplate(templates[lang].fieldClose).Execute(w, v); err != nil {
+			if err := newTemplate(lang, fragments(lang).FieldClose, opts).Execute(w, v); err != nil {
 				return err
 			}
 		} else {
-			tpl := templates[lang].lastFieldClose
+			tpl := fragments(lang).LastFieldClose
 			if tpl == "" {
-				tpl = templates[lang].fieldClose
+				tpl = fragments(lang).FieldClose
 			}
-			if err := newTemplate(tpl).Execute(w, v); err != nil {
+			if err := newTemplate(lang, tpl, opts).Execute(w, v); err != nil {
 				return err
 			}
 		}
 	}
-	return newTemplate(templates[lang].structClose).Execute(w, t)
+	return newTemplate(lang, fragments(lang).StructClose, opts).Execute(w, t)
 }
 
 // Field is a struct field
@@ -199,42 +347,90 @@ type Field struct {
 	Tag         string
 }
 
-func (t *Field) Template(w io.Writer, lang Language) error {
-	jsonName := strings.Split(GetTag("json", t.Tag), ",")[0]
-	if jsonName != "" {
-		t.Name = jsonName
-	}
+// field is a Field as drawn, with its name resolved from the json tag.
+type field struct {
+	*Field
+	Name     string
+	Body     string
+	Optional bool
+}
+
+func (t *Field) Template(w io.Writer, lang Language, opts Options) error {
+	f := field{Field: t}
+	var jsonOpts []string
+	f.Name, jsonOpts = t.jsonName(opts)
 
 	// Golang allows any valid JSON property name to be provided in the JSON tag.
 	// Some aren't valid JS identifiers, so we want to quote them.
 	switch lang {
 	case Typescript, Flow:
-		if propertyShouldBeQuoted(t.Name) {
-			t.Name = fmt.Sprintf(`"%s"`, t.Name)
+		if propertyShouldBeQuoted(f.Name) {
+			f.Name = fmt.Sprintf(`"%s"`, f.Name)
+		}
+	case JSDoc:
+		// @property only names identifiers, quoted names are read as types.
+		if propertyShouldBeQuoted(f.Name) {
+			return fmt.Errorf("field %s: %q is not a valid JSDoc property name, rename it in its json tag", t.Name, f.Name)
 		}
 	default:
 	}
 
-	basicType, isBasic := t.Type.(*Basic)
+	f.Optional = t.Type.IsPointer() || hasOption(jsonOpts, "omitempty")
 
-	if err := newTemplate(templates[lang].fieldName).Execute(w, t); err != nil {
-		return err
+	property := fragments(lang).Property
+	if property == "" {
+		if err := newTemplate(lang, fragments(lang).FieldName, opts).Execute(w, f); err != nil {
+			return err
+		}
 	}
 
-	if lang == Typescript && isBasic {
+	typ := t.Type
+	if lang == Typescript || lang == JSDoc {
 		// Special case for TS: top-level nullable type is written as
 		// field?: T
 		// but if that's the type parameter, it should become
 		// T | undefined
 		// So, we drop the Pointer flag for top-level types, since the field
-		// already has "?" in it.
-		t.Type = &Basic{
-			Type:    basicType.Type,
-			Pointer: false,
+		// already has "?" in it. JSDoc marks the same fields as [optional].
+		switch x := typ.(type) {
+		case *Basic:
+			typ = &Basic{
+				Type:    x.Type,
+				Pointer: false,
+			}
+		case *Generic:
+			typ = &Generic{
+				Type: x.Type,
+				Args: x.Args,
+			}
 		}
 	}
 
-	// If there is an override type on the struct field
+	typ = t.override(typ)
+	if property == "" {
+		return typ.Template(w, lang, opts)
+	}
+
+	buf := bytes.Buffer{}
+	if err := typ.Template(&buf, lang, opts); err != nil {
+		return err
+	}
+	f.Body = buf.String()
+	return newTemplate(lang, property, opts).Execute(w, f)
+}
+
+// jsonName returns the name the field is encoded by in JSON, from its json tag
+// or else its name, and the options of its json tag, such as "omitempty".
+func (t *Field) jsonName(opts Options) (string, []string) {
+	jsonOpts := strings.Split(GetTag("json", t.Tag), ",")
+	if jsonOpts[0] != "" {
+		return jsonOpts[0], jsonOpts[1:]
+	}
+	return opts.Naming.Apply(t.Name), jsonOpts[1:]
+}
+
+// override returns typ, or the type overriding it in the field's tw tag.
+func (t *Field) override(typ TypeSpec) TypeSpec {
 	override := strings.Split(GetTag("tw", t.Tag), ",")
 	switch len(override) {
 	case 2:
@@ -242,19 +438,29 @@ func (t *Field) Template(w io.Writer, lang Language) error {
 		if err != nil {
 			log.WithError(err).Errorf("error parsing bool for type %s", t.Name)
 		}
-		t.Type = &Basic{
+		typ = &Basic{
 			Type:    string(override[0]),
 			Pointer: ptr,
 		}
 	case 1:
 		if string(override[0]) != "" {
-			t.Type = &Basic{
+			typ = &Basic{
 				Type:    string(override[0]),
 				Pointer: false,
 			}
 		}
 	}
-	return t.Type.Template(w, lang)
+	return typ
+}
+
+// hasOption reports whether a tag option, such as "omitempty", is in opts.
+func hasOption(opts []string, opt string) bool {
+	for _, o := range opts {
+		if strings.TrimSpace(o) == opt {
+			return true
+		}
+	}
+	return false
 }
 
 func GetTag(tag string, tags string) string {
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/natdm/typewriter/ir"
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -37,7 +39,7 @@ func (s *TemplateTestSuite) TestFlowTemplatePackageTypes() {
 	}
 
 	buf := new(bytes.Buffer)
-	s.Require().NoError(p.Template(buf, Flow))
+	s.Require().NoError(p.Template(buf, Flow, Options{}))
 	expected := `
 // ... Comment
 export type Maps = {
@@ -60,7 +62,7 @@ func (s *TemplateTestSuite) TestFlowTemplateArrayOfMaps() {
 	}
 
 	buf := new(bytes.Buffer)
-	s.Require().NoError(p.Template(buf, Flow))
+	s.Require().NoError(p.Template(buf, Flow, Options{}))
 	expected := `
 // ... Comment
 export type Array = Array<{ [key: number]: ?string }>`
@@ -80,7 +82,7 @@ func (s *TemplateTestSuite) TestFlowTemplateArrayOfCustom() {
 	}
 
 	buf := new(bytes.Buffer)
-	s.Require().NoError(p.Template(buf, Flow))
+	s.Require().NoError(p.Template(buf, Flow, Options{}))
 	expected := `
 // ... Comment
 export type CustomTypeArray = CustomType[]`
@@ -100,7 +102,7 @@ func (s *TemplateTestSuite) TestFlowTemplateArrayOfInts() {
 	}
 
 	buf := new(bytes.Buffer)
-	s.Require().NoError(p.Template(buf, Flow))
+	s.Require().NoError(p.Template(buf, Flow, Options{}))
 	expected := `
 // ... Comment
 export type Array = number[]`
@@ -118,7 +120,7 @@ func (s *TemplateTestSuite) TestFlowTemplateMapOfStringsInts() {
 	}
 
 	buf := new(bytes.Buffer)
-	s.Require().NoError(p.Template(buf, Flow))
+	s.Require().NoError(p.Template(buf, Flow, Options{}))
 	expected := `
 // ... Comment
 export type MapOfStringInts = { [key: string]: number }`
@@ -133,7 +135,7 @@ func (s *TemplateTestSuite) TestFlowInt() {
 	}
 
 	buf := new(bytes.Buffer)
-	s.Require().NoError(p.Template(buf, Flow))
+	s.Require().NoError(p.Template(buf, Flow, Options{}))
 	expected := `
 // ... Comment
 export type AliasToInt = number`
@@ -148,9 +150,202 @@ func (s *TemplateTestSuite) TestTime() {
 	}
 
 	buf := new(bytes.Buffer)
-	s.Require().NoError(p.Template(buf, Flow))
+	s.Require().NoError(p.Template(buf, Flow, Options{}))
 	expected := `
 // ... Comment
 export type TimeToDate = Date`
 	s.Equal(expected, buf.String())
 }
+
+func (s *TemplateTestSuite) TestJSDocTemplateStruct() {
+	p := &PackageType{
+		Name:    "User",
+		Comment: "User is a user\n",
+		Type: &Struct{
+			Fields: []Field{
+				{
+					Name:       "Name",
+					Type:       &Basic{"string", false},
+					DocComment: "Name is the\ndisplay name",
+					Tag:        `json:"name"`,
+				}, {
+					Name:        "Age",
+					Type:        &Basic{"int", true},
+					LineComment: "in years",
+					Tag:         `json:"age"`,
+				}, {
+					Name: "Tags",
+					Type: &Array{Type: &Basic{"string", false}},
+					Tag:  `json:"tags,omitempty"`,
+				},
+			},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	s.Require().NoError(p.Template(buf, JSDoc, Options{}))
+	expected := `
+/**
+ * User is a user
+ * @typedef {Object} User
+ * @property {string} name - Name is the display name
+ * @property {number} [age] - in years
+ * @property {string[]} [tags]
+ */`
+	s.Equal(expected, buf.String())
+}
+
+func (s *TemplateTestSuite) TestJSDocTemplateMap() {
+	p := &PackageType{
+		Name: "People",
+		Type: &Map{
+			Key:   &Basic{"string", false},
+			Value: &Basic{"Person", true},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	s.Require().NoError(p.Template(buf, JSDoc, Options{}))
+	expected := `
+/**
+ * @typedef {Object<string, ?Person>} People
+ */`
+	s.Equal(expected, buf.String())
+}
+
+func (s *TemplateTestSuite) TestTSTemplateInterface() {
+	p := &PackageType{
+		Name: "Example",
+		Type: &Struct{
+			Fields: []Field{
+				{
+					Name: "Basic",
+					Type: &Basic{"string", false},
+					Tag:  `json:"basic"`,
+				}, {
+					Name: "Pointer",
+					Type: &Basic{"Event", true},
+					Tag:  `json:"event_pointer"`,
+				},
+			},
+			Embedded: []string{"Embedded", "Other"},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	opts := Options{TS: TSOptions{Export: true, Interface: true, Readonly: true}}
+	s.Require().NoError(p.Template(buf, Typescript, opts))
+	expected := `
+export interface Example extends Embedded, Other {
+	readonly basic: string,
+	readonly event_pointer?: Event,
+}`
+	s.Equal(expected, buf.String())
+}
+
+func (s *TemplateTestSuite) TestTSTemplateExtendsOnlyEmbedding() {
+	opts := Options{TS: TSOptions{Export: true, Extends: true}}
+
+	p := &PackageType{
+		Name: "Plain",
+		Type: &Struct{Fields: []Field{{Name: "Name", Type: &Basic{"string", false}, Tag: `json:"name"`}}},
+	}
+	buf := new(bytes.Buffer)
+	s.Require().NoError(p.Template(buf, Typescript, opts))
+	s.Equal(`
+export type Plain = {
+	name: string,
+}`, buf.String())
+
+	p = &PackageType{
+		Name: "Ids",
+		Type: &Array{Type: &Basic{"int", false}},
+	}
+	buf.Reset()
+	s.Require().NoError(p.Template(buf, Typescript, opts))
+	s.Equal(`
+export type Ids = number[]`, buf.String())
+}
+
+func (s *TemplateTestSuite) TestTSDrawNamespace() {
+	types := map[string]*ir.Decl{
+		"Id": {Name: "Id", Type: &ir.Type{Kind: ir.Basic, Name: "int"}},
+		"User": {Name: "User", Type: &ir.Type{Kind: ir.Struct, Fields: []*ir.Field{
+			{Name: "Id", Type: &ir.Type{Kind: ir.Basic, Name: "Id"}, Tag: `json:"id"`},
+		}}},
+	}
+
+	buf := new(bytes.Buffer)
+	ct, err := Draw(types, buf, Typescript, Options{TS: TSOptions{Namespace: "Api"}}, log.New())
+	s.Require().NoError(err)
+	s.Equal(2, ct)
+	expected := `// Automatically generated by typewriter. Do not edit.
+// http://www.github.com/natdm/typewriter
+
+declare namespace Api {
+
+	type Id = number
+
+	type User = {
+		id: Id,
+	}
+}
+`
+	s.Equal(expected, buf.String())
+}
+
+func (s *TemplateTestSuite) TestFlowTemplateExactByDefault() {
+	fields := []Field{{Name: "Name", Type: &Basic{"string", false}, Tag: `json:"name"`}}
+	opts := Options{Flow: FlowOptions{Exact: true, Covariant: true}}
+
+	p := &PackageType{Name: "Exact", Type: &Struct{Fields: fields, Embedded: []string{"Base"}}}
+	buf := new(bytes.Buffer)
+	s.Require().NoError(p.Template(buf, Flow, opts))
+	s.Equal(`
+export type Exact = {|
+	...Base,
+	+name: string,
+|}`, buf.String(), "exact objects spread embedded types, as they cannot be intersected")
+
+	p = &PackageType{Name: "Inexact", Type: &Struct{Fields: fields, Inexact: true}}
+	buf.Reset()
+	s.Require().NoError(p.Template(buf, Flow, opts))
+	s.Equal(`
+export type Inexact = {
+	+name: string,
+	...
+}`, buf.String())
+}
+
+func (s *TemplateTestSuite) TestFlowTemplateReadOnly() {
+	p := &PackageType{
+		Name: "Strict",
+		Type: &Struct{
+			Fields: []Field{{Name: "Name", Type: &Basic{"string", true}, Tag: `json:"name"`}},
+			Strict: true,
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	s.Require().NoError(p.Template(buf, Flow, Options{Flow: FlowOptions{ReadOnly: true}}))
+	s.Equal(`
+export type Strict = $ReadOnly<{|
+	name: ?string,
+|}>`, buf.String())
+
+	p = &PackageType{Name: "Embedding", Type: &Struct{Fields: p.Type.(*Struct).Fields, Embedded: []string{"Base"}}}
+	buf.Reset()
+	s.Require().NoError(p.Template(buf, Flow, Options{Flow: FlowOptions{ReadOnly: true}}))
+	s.Equal(`
+export type Embedding = $ReadOnly<Base & {
+	name: ?string,
+}>`, buf.String())
+
+	buf.Reset()
+	s.Require().NoError(p.Template(buf, Flow, Options{Flow: FlowOptions{ReadOnly: true, Exact: true}}))
+	s.Equal(`
+export type Embedding = $ReadOnly<{|
+	...Base,
+	name: ?string,
+|}>`, buf.String())
+}
@@ -0,0 +1,351 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package typewriter converts Go types to types in other languages.
+//
+// Generate is the single entry point for programs using typewriter as a library.
+// It parses Go files exactly like the typewriter command does and draws the
+// types in every configured language.
+package typewriter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/natdm/typewriter/diag"
+	"github.com/natdm/typewriter/ir"
+	"github.com/natdm/typewriter/parse"
+	"github.com/natdm/typewriter/template"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	errNoOutput    = errors.New("no output writer")
+	errAmbientBoth = errors.New("only one of the Typescript namespace and module can be set")
+)
+
+// Config configures a Generate run.
+type Config struct {
+	// Types are drawn instead of parsing Go files when set, e.g. a model
+	// decoded with ir.Decode. The type filters, Roots and MarkedOnly still
+	// select among them.
+	Types map[string]*ir.Decl
+
+	// Files are the Go files to parse types from. When empty, Dir is parsed instead.
+	Files []string
+
+	// Dir is the directory to parse types from. Defaults to the working directory.
+	Dir string
+
+	// Recursive parses every directory below Dir too.
+	Recursive bool
+
+	// Include are globs of the Go files to parse, such as "api/**" or
+	// "*_payload.go". When empty, every file is parsed.
+	Include []string
+
+	// Exclude are globs of Go files and directories not to parse, such as
+	// "vendor", "**/testdata/**" or "*_gen.go". Excluded directories are not
+	// walked at all.
+	//
+	// Globs are matched against paths relative to Dir, where "**" matches any
+	// number of directories. Globs without a slash match base names too.
+	Exclude []string
+
+	// IncludeTypes, when set, parses only the types whose name it matches.
+	IncludeTypes *regexp.Regexp
+
+	// ExcludeTypes skips the types whose name it matches.
+	ExcludeTypes *regexp.Regexp
+
+	// ExportedOnly skips unexported types.
+	ExportedOnly bool
+
+	// UnexportedFields draws the unexported fields of structs too. They are
+	// skipped by default, as encoding/json skips them.
+	UnexportedFields bool
+
+	// Build selects the Go files to parse by build constraints and GOOS and
+	// GOARCH file name suffixes, like go build does. Defaults to build.Default,
+	// the host platform without extra tags.
+	Build *build.Context
+
+	// MarkedOnly draws only the types marked with an @typewriter comment
+	// flag or a //tw:generate directive, and every type they refer to.
+	MarkedOnly bool
+
+	// Roots, when set, draws only the named types, such as "Response" or
+	// "api.Response", and every type they refer to, across packages. Unknown
+	// roots are an error.
+	Roots []string
+
+	// Unions draw interfaces as one of their implementations, by the name of
+	// the interface, such as "Shape" or "shapes.Shape", instead of skipping
+	// them. They replace the union declared by an interface's //tw:union
+	// directive.
+	Unions map[string]parse.Union
+
+	// Language is the language to draw types in. Language, Options and Out
+	// are drawn as the first target, when Out is set.
+	Language template.Language
+
+	// Options are the per-language drawing options.
+	Options template.Options
+
+	// Targets are drawn after Language, from the same parsed types.
+	Targets []Target
+
+	// ExpandEmbedded expands embedded structs into their fields. Languages
+	// without intersection types require it.
+	ExpandEmbedded bool
+
+	// Collisions is how types of the same name declared in different
+	// packages are handled. They are an error by default.
+	Collisions parse.Collisions
+
+	// Out receives the drawn types.
+	Out io.Writer
+
+	// Logger receives warnings, and every skipped or drawn type at debug level.
+	// A nil Logger discards everything.
+	Logger log.FieldLogger
+
+	// Diagnostics, when set, collects every skipped type, field and constant
+	// with its position, instead of logging them as warnings. Watch empties it
+	// before every parse.
+	Diagnostics *diag.List
+}
+
+// Target is a language to draw the types in, and where to draw them.
+type Target struct {
+	Language template.Language
+	Options  template.Options
+	Out      io.Writer
+
+	// Split draws a file per Go package or per type instead of writing to Out.
+	// Each file is written to the writer OutFile returns for its name.
+	Split   template.Split
+	OutFile func(name string) (io.Writer, error)
+
+	// Index draws an index file re-exporting every split file.
+	Index bool
+}
+
+// Result is the outcome of a Generate run.
+type Result struct {
+	// Types are the parsed types, by name.
+	Types map[string]*ir.Decl
+
+	// Count is the number of types drawn, the same for every target.
+	Count int
+}
+
+// Generate parses the configured Go files once and draws their types to every
+// target. The context is checked between parsing and each target.
+func Generate(ctx context.Context, c Config) (Result, error) {
+	targets := c.targets()
+	if len(targets) == 0 {
+		return Result{}, errNoOutput
+	}
+	for _, t := range targets {
+		if (t.Split == template.SplitNone && t.Out == nil) || (t.Split != template.SplitNone && t.OutFile == nil) {
+			return Result{}, errNoOutput
+		}
+		if err := c.validate(t); err != nil {
+			return Result{}, err
+		}
+	}
+
+	types, err := Parse(ctx, c)
+	if err != nil {
+		return Result{}, err
+	}
+
+	res := Result{Types: types}
+	for _, t := range targets {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		var ct int
+		if t.Split == template.SplitNone {
+			ct, err = template.Draw(types, t.Out, t.Language, t.Options, c.logger())
+		} else {
+			ct, err = template.DrawFiles(types, t.OutFile, t.Split, t.Index, t.Language, t.Options, c.logger())
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("%s: %v", t.Language, err)
+		}
+		res.Count = ct
+	}
+	return res, nil
+}
+
+// Parse parses the configured Go files without drawing them. When c.Types is
+// set, the types of it the type filters, roots and marked types select are
+// returned instead.
+func Parse(ctx context.Context, c Config) (map[string]*ir.Decl, error) {
+	if c.Types != nil {
+		return parse.Select(c.Types, c.logger(), c.parseOptions())
+	}
+
+	files, err := c.files()
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return parse.Files(files, c.logger(), c.parseOptions())
+}
+
+// files returns the Go files to parse, without the excluded ones. Excluded
+// directories are not walked.
+func (c Config) files() ([]string, error) {
+	dir := c.Dir
+	if dir == "" {
+		dir = "./"
+	}
+	var files []string
+	if len(c.Files) == 0 {
+		if err := parse.DirectoryFunc(dir, c.Recursive, c.skip(dir), &files); err != nil {
+			return nil, err
+		}
+		return files, nil
+	}
+	for _, f := range c.Files {
+		skipped, err := c.skip(dir)(f, false)
+		if err != nil {
+			return nil, err
+		}
+		if !skipped {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// skip returns whether a file or directory below dir is skipped: when it
+// matches any of c.Exclude, or, for a file, none of c.Include or not c.Build.
+func (c Config) skip(dir string) func(string, bool) (bool, error) {
+	constraints := parse.Constraints(c.Build)
+	return func(name string, isDir bool) (bool, error) {
+		rel, err := filepath.Rel(dir, name)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = name
+		}
+		rel = filepath.ToSlash(rel)
+
+		for _, glob := range c.Exclude {
+			match := matchGlob
+			if isDir {
+				match = matchDir
+			}
+			ok, err := match(glob, rel)
+			if err != nil {
+				return false, fmt.Errorf("exclude %q: %v", glob, err)
+			}
+			if ok {
+				c.logger().WithField("path", name).Debug("excluded path")
+				return true, nil
+			}
+		}
+		if isDir {
+			return false, nil
+		}
+		included := len(c.Include) == 0
+		for _, glob := range c.Include {
+			ok, err := matchGlob(glob, rel)
+			if err != nil {
+				return false, fmt.Errorf("include %q: %v", glob, err)
+			}
+			if ok {
+				included = true
+				break
+			}
+		}
+		if !included {
+			c.logger().WithField("path", name).Debug("file not included")
+			return true, nil
+		}
+		skipped, err := constraints(name, false)
+		if err != nil {
+			return false, err
+		}
+		if skipped {
+			c.logger().WithField("path", name).Debug("file excluded by build constraints")
+		}
+		return skipped, nil
+	}
+}
+
+// parseOptions returns the options files are parsed with.
+func (c Config) parseOptions() parse.Options {
+	return parse.Options{
+		ExpandEmbedded:   c.ExpandEmbedded,
+		Collisions:       c.Collisions,
+		IncludeTypes:     c.IncludeTypes,
+		ExcludeTypes:     c.ExcludeTypes,
+		ExportedOnly:     c.ExportedOnly,
+		UnexportedFields: c.UnexportedFields,
+		MarkedOnly:       c.MarkedOnly,
+		Roots:            c.Roots,
+		Unions:           c.Unions,
+		Build:            c.Build,
+		Diagnostics:      c.Diagnostics,
+	}
+}
+
+// logger returns the configured logger, or one discarding everything.
+func (c Config) logger() log.FieldLogger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	l := log.New()
+	l.Out = ioutil.Discard
+	return l
+}
+
+// targets returns every target to draw, starting with Language when Out is set.
+func (c Config) targets() []Target {
+	if c.Out == nil {
+		return c.Targets
+	}
+	return append([]Target{{Language: c.Language, Options: c.Options, Out: c.Out}}, c.Targets...)
+}
+
+// validate checks the settings of a target before anything is parsed.
+func (c Config) validate(t Target) error {
+	if t.Language.Spec().ExpandEmbedded {
+		if c.Types == nil && !c.ExpandEmbedded {
+			return fmt.Errorf("embedded structs have to be expanded for %s, which does not support intersection types", t.Language)
+		}
+		for name, d := range c.Types {
+			if d.Type != nil && len(d.Type.Embedded) > 0 {
+				return fmt.Errorf("%s has embedded structs, which have to be expanded for %s", name, t.Language)
+			}
+		}
+	}
+	if !t.Options.Naming.Valid() {
+		return fmt.Errorf("unknown naming strategy %q", t.Options.Naming)
+	}
+	if t.Options.TS.Namespace != "" && t.Options.TS.Module != "" {
+		return errAmbientBoth
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package typewriter
+
+import (
+	"bytes"
+	"context"
+	"go/ast"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/natdm/typewriter/ir"
+	"github.com/natdm/typewriter/template"
+	"github.com/stretchr/testify/suite"
+)
+
+type GenerateTestSuite struct {
+	suite.Suite
+}
+
+func TestGenerateTestSuite(t *testing.T) {
+	suite.Run(t, new(GenerateTestSuite))
+}
+
+func (s *GenerateTestSuite) TestGenerate() {
+	buf := new(bytes.Buffer)
+	res, err := Generate(context.Background(), Config{
+		Dir:      "./examples/package",
+		Language: template.Typescript,
+		Out:      buf,
+	})
+	s.Require().NoError(err)
+	s.Equal(3, res.Count)
+	s.Contains(res.Types, "Thing")
+	s.Contains(buf.String(), "type Thing = {\n\tname: number,\n}")
+}
+
+func (s *GenerateTestSuite) TestGenerateValidates() {
+	_, err := Generate(context.Background(), Config{Language: template.Elm, Out: new(bytes.Buffer)})
+	s.Error(err)
+
+	_, err = Generate(context.Background(), Config{Language: template.Flow})
+	s.Error(err)
+}
+
+func (s *GenerateTestSuite) TestGenerateCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Generate(ctx, Config{
+		Dir:      "./examples/package",
+		Language: template.Flow,
+		Out:      new(bytes.Buffer),
+	})
+	s.Equal(context.Canceled, err)
+}
+
+func (s *GenerateTestSuite) TestGenerateFromTypes() {
+	types := map[string]*ir.Decl{
+		"Id": {Name: "Id", Type: &ir.Type{Kind: ir.Basic, Name: "int"}},
+	}
+	buf := new(bytes.Buffer)
+	res, err := Generate(context.Background(), Config{
+		Types:    types,
+		Dir:      "./does/not/exist",
+		Language: template.Flow,
+		Out:      buf,
+	})
+	s.Require().NoError(err)
+	s.Equal(1, res.Count)
+	s.Contains(buf.String(), "export type Id = number")
+
+	types["Embeds"] = &ir.Decl{Name: "Embeds", Type: &ir.Type{Kind: ir.Struct, Embedded: []string{"Id"}}}
+	_, err = Generate(context.Background(), Config{Types: types, Language: template.Elm, ExpandEmbedded: true, Out: buf})
+	s.Error(err)
+}
+
+func (s *GenerateTestSuite) TestParseFromTypesSelects() {
+	types := map[string]*ir.Decl{
+		"Base": {Name: "Base", Type: &ir.Type{Kind: ir.Struct, Fields: []*ir.Field{{Name: "ID", Type: &ir.Type{Kind: ir.Basic, Name: "ID"}}}}},
+		"ID":   {Name: "ID", Type: &ir.Type{Kind: ir.Basic, Name: "int"}},
+		"User": {Name: "User", Type: &ir.Type{Kind: ir.Struct, Embedded: []string{"Base"}}, Marked: true},
+		"user": {Name: "user", Type: &ir.Type{Kind: ir.Basic, Name: "string"}},
+	}
+	names := func(c Config) []string {
+		c.Types = types
+		parsed, err := Parse(context.Background(), c)
+		s.Require().NoError(err)
+		var names []string
+		for name := range parsed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	s.Equal([]string{"Base", "ID"}, names(Config{Roots: []string{"Base"}}))
+	s.Equal([]string{"Base", "ID", "User"}, names(Config{MarkedOnly: true}))
+	s.Equal([]string{"Base", "ID", "User"}, names(Config{ExportedOnly: true}))
+	s.Equal([]string{"User", "user"}, names(Config{IncludeTypes: regexp.MustCompile("(?i)^user$")}))
+	s.Len(types, 4, "the types are left untouched")
+
+	_, err := Parse(context.Background(), Config{Types: types, Roots: []string{"Missing"}})
+	s.EqualError(err, "root type Missing not found")
+}
+
+func (s *GenerateTestSuite) TestGenerateTargets() {
+	ts, flow := new(bytes.Buffer), new(bytes.Buffer)
+	res, err := Generate(context.Background(), Config{
+		Dir:      "./examples/package",
+		Language: template.Typescript,
+		Out:      ts,
+		Targets: []Target{
+			{Language: template.Flow, Options: template.Options{Flow: template.FlowOptions{Exact: true}}, Out: flow},
+		},
+	})
+	s.Require().NoError(err)
+	s.Equal(3, res.Count)
+	s.Contains(ts.String(), "type Thing = {\n\tname: number,\n}")
+	s.Contains(flow.String(), "export type Thing = {|\n\tname: number,\n|}")
+
+	_, err = Generate(context.Background(), Config{
+		Dir:     "./examples/package",
+		Targets: []Target{{Language: template.Flow, Out: flow}, {Language: template.Elm, Out: ts}},
+	})
+	s.Error(err, "every target is validated before parsing")
+}
+
+func (s *GenerateTestSuite) TestGenerateExclude() {
+	res, err := Generate(context.Background(), Config{
+		Dir:      "./examples",
+		Exclude:  []string{"example_models.go", "package/*"},
+		Language: template.Typescript,
+		Out:      new(bytes.Buffer),
+	})
+	s.Require().NoError(err)
+	s.NotContains(res.Types, "Thing")
+	s.Contains(res.Types, "Data")
+}
+
+func (s *GenerateTestSuite) TestGenerateSplit() {
+	files := make(map[string]*bytes.Buffer)
+	res, err := Generate(context.Background(), Config{
+		Dir: "./examples/package",
+		Targets: []Target{{
+			Language: template.Typescript,
+			Split:    template.SplitType,
+			OutFile: func(name string) (io.Writer, error) {
+				files[name] = new(bytes.Buffer)
+				return files[name], nil
+			},
+		}},
+	})
+	s.Require().NoError(err)
+	s.Equal(3, res.Count)
+	s.Len(files, 3)
+	s.Contains(files["Thing.ts"].String(), "export type Thing = {\n\tname: number,\n}")
+
+	_, err = Generate(context.Background(), Config{
+		Dir:     "./examples/package",
+		Targets: []Target{{Language: template.Typescript, Split: template.SplitType, Out: new(bytes.Buffer)}},
+	})
+	s.Equal(errNoOutput, err)
+}
+
+func (s *GenerateTestSuite) TestGenerateFilters() {
+	types, err := Parse(context.Background(), Config{
+		Dir:       "./examples",
+		Recursive: true,
+		Include:   []string{"package/**"},
+	})
+	s.Require().NoError(err)
+	s.Len(types, 3)
+	s.Contains(types, "Thing")
+
+	types, err = Parse(context.Background(), Config{
+		Dir:          "./examples",
+		Recursive:    true,
+		Exclude:      []string{"package"},
+		IncludeTypes: regexp.MustCompile("^Data"),
+		ExcludeTypes: regexp.MustCompile("Type$"),
+	})
+	s.Require().NoError(err)
+	s.Len(types, 1)
+	s.Contains(types, "Data")
+
+	types, err = Parse(context.Background(), Config{Dir: "./examples", ExportedOnly: true})
+	s.Require().NoError(err)
+	for name := range types {
+		s.True(ast.IsExported(name), name)
+	}
+}
+
+func (s *GenerateTestSuite) TestGenerateRoots() {
+	types, err := Parse(context.Background(), Config{Dir: "./examples", Roots: []string{"People", "stubs.Time"}})
+	s.Require().NoError(err)
+	var names []string
+	for name := range types {
+		names = append(names, name)
+	}
+	s.ElementsMatch([]string{"People", "Person", "Time"}, names)
+
+	_, err = Parse(context.Background(), Config{Dir: "./examples", Roots: []string{"Missing"}})
+	s.EqualError(err, "root type Missing not found")
+}
+
+func (s *GenerateTestSuite) TestGenerateRootsSkipCollisions() {
+	dir, err := ioutil.TempDir("", "typewriter")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+	for pkg, src := range map[string]string{
+		"a": "package a\n\ntype Payload struct {\n\tID int\n}\n\ntype Config struct{}\n",
+		"b": "package b\n\ntype Config struct{}\n",
+	} {
+		s.Require().NoError(os.Mkdir(filepath.Join(dir, pkg), 0755))
+		s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, pkg, pkg+".go"), []byte(src), 0644))
+	}
+
+	buf := new(bytes.Buffer)
+	res, err := Generate(context.Background(), Config{Dir: dir, Recursive: true, Roots: []string{"Payload"}, Language: template.Typescript, Out: buf})
+	s.Require().NoError(err, "Config is not reached, so it does not collide")
+	s.Equal(1, res.Count)
+	s.Contains(buf.String(), "type Payload = {")
+
+	_, err = Parse(context.Background(), Config{Dir: dir, Recursive: true})
+	s.Error(err)
+}
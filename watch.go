@@ -0,0 +1,135 @@
+package typewriter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/natdm/typewriter/diag"
+	"github.com/natdm/typewriter/ir"
+	"github.com/natdm/typewriter/parse"
+)
+
+// This file contains the polling watcher, which parses files again as they change.
+
+var errWatchTypes = errors.New("types given in the config cannot be watched")
+
+// Watch parses the configured Go files and calls changed with their types,
+// then polls the files every interval and calls changed again whenever any
+// of them is saved, added or removed, until ctx is done.
+//
+// Bursts of saves are parsed once, after a whole interval passes without
+// another change. Only the files that changed are parsed again. Files that
+// fail to parse, e.g. while being edited, are logged and retried once they
+// change again.
+func Watch(ctx context.Context, c Config, interval time.Duration, changed func(map[string]*ir.Decl)) error {
+	if c.Types != nil {
+		return errWatchTypes
+	}
+	w := &watcher{c: c, files: make(map[string]*watched)}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pending := false
+	for {
+		dirty, err := w.scan()
+		if err != nil {
+			c.logger().WithError(err).Error("error listing files to watch")
+		}
+		if dirty {
+			pending = true
+		} else if pending {
+			pending = false
+			if types, err := w.parse(); err != nil {
+				c.logger().WithError(err).Error("error parsing files")
+			} else {
+				changed(types)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// watched is a watched file, and what it declared when it was last parsed.
+type watched struct {
+	modTime time.Time
+	size    int64
+
+	// file is nil until the file is parsed, and again once it changes.
+	file *parse.File
+
+	// diags are the diagnostics of parsing the file.
+	diags []diag.Diagnostic
+}
+
+type watcher struct {
+	c     Config
+	order []string
+	files map[string]*watched
+}
+
+// scan lists and stats every file, and reports whether any changed since the last scan.
+func (w *watcher) scan() (bool, error) {
+	names, err := w.c.files()
+	if err != nil {
+		return false, err
+	}
+
+	dirty := len(names) != len(w.files)
+	seen := make(map[string]*watched, len(names))
+	for i, name := range names {
+		if i >= len(w.order) || w.order[i] != name {
+			dirty = true
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return false, err
+		}
+		f, ok := w.files[name]
+		if !ok || !f.modTime.Equal(info.ModTime()) || f.size != info.Size() {
+			f = &watched{modTime: info.ModTime(), size: info.Size()}
+			dirty = true
+		}
+		seen[name] = f
+	}
+	w.order, w.files = names, seen
+	return dirty, nil
+}
+
+// parse parses the files that changed, and merges them with the rest. The
+// diagnostics of the files that did not change are reported again.
+func (w *watcher) parse() (map[string]*ir.Decl, error) {
+	if w.c.Diagnostics != nil {
+		w.c.Diagnostics.Reset()
+	}
+	files := make([]*parse.File, 0, len(w.order))
+	for _, name := range w.order {
+		f := w.files[name]
+		if f.file == nil {
+			opts := w.c.parseOptions()
+			if opts.Diagnostics != nil {
+				opts.Diagnostics = new(diag.List)
+			}
+			parsed, err := parse.ParseFile(name, w.c.logger(), opts)
+			if err != nil {
+				return nil, err
+			}
+			w.c.logger().WithField("file", name).Debug("parsed changed file")
+			f.file = parsed
+			if opts.Diagnostics != nil {
+				f.diags = opts.Diagnostics.Diagnostics(diag.Info)
+			}
+		}
+		for _, d := range f.diags {
+			w.c.Diagnostics.Report(d)
+		}
+		files = append(files, f.file)
+	}
+	return parse.Merge(files, w.c.logger(), w.c.parseOptions())
+}
@@ -0,0 +1,109 @@
+package typewriter
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/natdm/typewriter/diag"
+	"github.com/natdm/typewriter/ir"
+	"github.com/stretchr/testify/suite"
+)
+
+type WatchTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestWatchTestSuite(t *testing.T) {
+	suite.Run(t, new(WatchTestSuite))
+}
+
+func (s *WatchTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "typewriter")
+	s.Require().NoError(err)
+	s.dir = dir
+}
+
+func (s *WatchTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+func (s *WatchTestSuite) write(name, src string) {
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(s.dir, name), []byte("package models\n\n"+src), 0644))
+}
+
+func (s *WatchTestSuite) TestWatch() {
+	s.write("a.go", "type A int\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runs := make(chan map[string]*ir.Decl)
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, Config{Dir: s.dir}, 10*time.Millisecond, func(types map[string]*ir.Decl) {
+			runs <- types
+		})
+	}()
+
+	next := func() map[string]*ir.Decl {
+		select {
+		case types := <-runs:
+			return types
+		case <-time.After(5 * time.Second):
+			s.FailNow("no change seen")
+			return nil
+		}
+	}
+
+	s.Contains(next(), "A")
+	s.write("b.go", "type B string\n")
+	types := next()
+	s.Contains(types, "A")
+	s.Contains(types, "B")
+
+	s.Require().NoError(os.Remove(filepath.Join(s.dir, "a.go")))
+	s.NotContains(next(), "A")
+
+	cancel()
+	s.Equal(context.Canceled, <-done)
+}
+
+func (s *WatchTestSuite) TestWatchDiagnostics() {
+	s.write("a.go", "type A chan int\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var diags diag.List
+	runs := make(chan []diag.Diagnostic)
+	go Watch(ctx, Config{Dir: s.dir, Diagnostics: &diags}, 10*time.Millisecond, func(map[string]*ir.Decl) {
+		runs <- diags.Diagnostics(diag.Info)
+	})
+
+	next := func() []diag.Diagnostic {
+		select {
+		case d := <-runs:
+			return d
+		case <-time.After(5 * time.Second):
+			s.FailNow("no change seen")
+			return nil
+		}
+	}
+
+	s.Len(next(), 1)
+
+	// a.go is not parsed again, but its diagnostics are still reported.
+	s.write("b.go", "type B func()\n")
+	d := next()
+	s.Require().Len(d, 2)
+	s.Equal("A", d[0].Type)
+	s.Equal("B", d[1].Type)
+}
+
+func (s *WatchTestSuite) TestWatchTypes() {
+	err := Watch(context.Background(), Config{Types: map[string]*ir.Decl{}}, time.Second, nil)
+	s.Equal(errWatchTypes, err)
+}